@@ -0,0 +1,253 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Checksum package computes the hashes of files and the file verification
+// codes of files, directories and archives the way the catalog does
+package checksum
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Sums are the hashes of a file and the file verification code of its
+// content. Directories have no hashes of their own.
+type Sums struct {
+	Path                 string `json:"path"`
+	Sha256               string `json:"sha256,omitempty"`
+	Sha1                 string `json:"sha1,omitempty"`
+	Md5                  string `json:"md5,omitempty"`
+	FileVerificationCode string `json:"file_verification_code"`
+	// number of files the file verification code is computed from
+	Files int `json:"files"`
+}
+
+// header of version 2 file verification codes
+const fvcHeader = "FVC2\x00"
+
+// FileVerificationCode() gives the file verification code of files with the
+// given hex sha256 sums. It is the header "FVC2\x00" followed by the sha256 of
+// the concatenated raw sha256 sums of the files in ascending order, given in hex.
+// Sums which are not valid hex are left out.
+func FileVerificationCode(sha256Sums []string) string {
+	sorted := make([][]byte, 0, len(sha256Sums))
+	for _, sum := range sha256Sums {
+		raw, err := hex.DecodeString(sum)
+		if err != nil {
+			continue
+		}
+		sorted = append(sorted, raw)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	hash := sha256.New()
+	for _, sum := range sorted {
+		hash.Write(sum)
+	}
+	return hex.EncodeToString(append([]byte(fvcHeader), hash.Sum(nil)...))
+}
+
+// Compute() gives the sums of a file, directory or archive. The file verification
+// code of a directory or an archive is computed from the regular files in it,
+// the one of any other file from the file itself.
+func Compute(ctx context.Context, path string) (*Sums, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	sums := &Sums{Path: path}
+	var fileSums []string
+	if info.IsDir() {
+		fileSums, err = directorySums(ctx, path)
+	} else {
+		fileSums, err = fileSumsOf(ctx, path, sums)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sums.FileVerificationCode = FileVerificationCode(fileSums)
+	sums.Files = len(fileSums)
+	return sums, nil
+}
+
+// FileSha256() gives the hex sha256 sum of the file
+func FileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	sum, err := sha256Of(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading %s", path)
+	}
+	return sum, nil
+}
+
+// fileSumsOf() sets the hashes of the file and gives the sha256 sums of the
+// files in it if it is an archive, or its own sha256 sum otherwise
+func fileSumsOf(ctx context.Context, path string, sums *Sums) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	sha256Hash, sha1Hash, md5Hash := sha256.New(), sha1.New(), md5.New()
+	if _, err = io.Copy(io.MultiWriter(sha256Hash, sha1Hash, md5Hash), file); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+	sums.Sha256 = hex.EncodeToString(sha256Hash.Sum(nil))
+	sums.Sha1 = hex.EncodeToString(sha1Hash.Sum(nil))
+	sums.Md5 = hex.EncodeToString(md5Hash.Sum(nil))
+
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zipSums(ctx, path)
+	case isTar(name):
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return tarSums(ctx, path, file)
+	}
+	return []string{sums.Sha256}, nil
+}
+
+// isTar() checks if the file name is the one of a plain or compressed tar archive
+func isTar(name string) bool {
+	for _, suffix := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tbz"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tarSums() gives the sha256 sums of the regular files in the tar archive
+func tarSums(ctx context.Context, path string, file io.Reader) ([]string, error) {
+	name := strings.ToLower(filepath.Base(path))
+	var reader io.Reader = file
+	switch {
+	case strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz"):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decompressing %s", path)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case strings.HasSuffix(name, ".bz2") || strings.HasSuffix(name, ".tbz"):
+		reader = bzip2.NewReader(file)
+	}
+	tarReader := tar.NewReader(reader)
+	fileSums := []string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return fileSums, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading archive %s", path)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		sum, err := sha256Of(tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s in archive %s", header.Name, path)
+		}
+		fileSums = append(fileSums, sum)
+	}
+}
+
+// zipSums() gives the sha256 sums of the regular files in the zip archive
+func zipSums(ctx context.Context, path string) ([]string, error) {
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading archive %s", path)
+	}
+	defer zipReader.Close()
+	fileSums := []string{}
+	for _, zipFile := range zipReader.File {
+		if !zipFile.Mode().IsRegular() {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		reader, err := zipFile.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s in archive %s", zipFile.Name, path)
+		}
+		sum, err := sha256Of(reader)
+		reader.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s in archive %s", zipFile.Name, path)
+		}
+		fileSums = append(fileSums, sum)
+	}
+	return fileSums, nil
+}
+
+// directorySums() gives the sha256 sums of the regular files in the directory and its sub directories
+func directorySums(ctx context.Context, path string) ([]string, error) {
+	fileSums := []string{}
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		sum, err := sha256Of(file)
+		if err != nil {
+			return errors.Wrapf(err, "error reading %s", filePath)
+		}
+		fileSums = append(fileSums, sum)
+		return nil
+	})
+	return fileSums, err
+}
+
+// sha256Of() gives the hex sha256 sum of the content of the reader
+func sha256Of(reader io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package checksum
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// files of the test archives and directory by name
+var testFiles = map[string]string{
+	"pkg/README":      "hello\n",
+	"pkg/src/main.c":  "int main() { return 0; }\n",
+	"pkg/src/empty.h": "",
+}
+
+// writeTarGz() writes the test files into a tar.gz archive
+func writeTarGz(tester *testing.T, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		tester.Fatal("failed to create archive", err)
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0755})
+	for name, content := range testFiles {
+		tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tarWriter.Write([]byte(content))
+	}
+	tarWriter.WriteHeader(&tar.Header{Name: "pkg/link", Typeflag: tar.TypeSymlink, Linkname: "README"})
+	if err = tarWriter.Close(); err != nil {
+		tester.Fatal("failed to write archive", err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		tester.Fatal("failed to write archive", err)
+	}
+}
+
+// writeZip() writes the test files into a zip archive
+func writeZip(tester *testing.T, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		tester.Fatal("failed to create archive", err)
+	}
+	defer file.Close()
+	zipWriter := zip.NewWriter(file)
+	for name, content := range testFiles {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			tester.Fatal("failed to write archive", err)
+		}
+		writer.Write([]byte(content))
+	}
+	if err = zipWriter.Close(); err != nil {
+		tester.Fatal("failed to write archive", err)
+	}
+}
+
+// TestFileVerificationCode checks the file verification code of the test
+// package against the one the catalog computed for it
+func TestFileVerificationCode(tester *testing.T) {
+	sums, err := Compute(context.Background(), "../../testdir/packages/openid-client-4.9.1_test.zip")
+	if err != nil {
+		tester.Fatal("failed to compute sums", err)
+	}
+	expected := "46564332008de01dcc150bcf6673a576d4c438b442afbb61d2cc98017234e44d9e338f19e8"
+	if sums.FileVerificationCode != expected {
+		tester.Errorf("Expected file verification code %s but got %s", expected, sums.FileVerificationCode)
+	}
+}
+
+// TestArchiveFileVerificationCode checks if directories and archives
+// with the same files have the same file verification code
+func TestArchiveFileVerificationCode(tester *testing.T) {
+	dir := tester.TempDir()
+	for name, content := range testFiles {
+		path := filepath.Join(dir, "tree", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			tester.Fatal("failed to write file", err)
+		}
+	}
+	writeTarGz(tester, filepath.Join(dir, "pkg.tar.gz"))
+	writeZip(tester, filepath.Join(dir, "pkg.zip"))
+
+	var codes []string
+	for _, name := range []string{"tree", "pkg.tar.gz", "pkg.zip"} {
+		sums, err := Compute(context.Background(), filepath.Join(dir, name))
+		if err != nil {
+			tester.Fatal("failed to compute sums of "+name, err)
+		}
+		if sums.Files != len(testFiles) {
+			tester.Errorf("Expected %d files in %s but got %d", len(testFiles), name, sums.Files)
+		}
+		codes = append(codes, sums.FileVerificationCode)
+	}
+	if codes[0] != codes[1] || codes[0] != codes[2] {
+		tester.Errorf("Expected the same file verification code for the directory and the archives but got %v", codes)
+	}
+}
+
+// TestFileSums checks the hashes of a plain file
+func TestFileSums(tester *testing.T) {
+	path := filepath.Join(tester.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		tester.Fatal("failed to write file", err)
+	}
+	sums, err := Compute(context.Background(), path)
+	if err != nil {
+		tester.Fatal("failed to compute sums", err)
+	}
+	if sums.Sha256 != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		tester.Errorf("Unexpected sha256 %s", sums.Sha256)
+	}
+	if sums.Sha1 != "f572d396fae9206628714fb2ce00f72e94f2258f" {
+		tester.Errorf("Unexpected sha1 %s", sums.Sha1)
+	}
+	if sums.Md5 != "b1946ac92492d2347c6235b4d2611184" {
+		tester.Errorf("Unexpected md5 %s", sums.Md5)
+	}
+}
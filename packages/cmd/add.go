@@ -12,16 +12,16 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
 	"wrs/catalog/ccli/packages/yaml"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	graph "github.com/hasura/go-graphql-client"
@@ -31,32 +31,35 @@ import (
 
 // Add() handles uploading a logical part or a part profile
 // to the catalog from a yml file
-func Add(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Add(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// create a cobra command
 	addCmd := &cobra.Command{
 		Use:   "add",
 		Short: "Add a specific component(part or profile) to Software Parts Catalog.",
 		// the function to be executed when the command is ran
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide the component type to be added(i.e. part or profile). For more info run help")
+			return usageError(errors.New("Please provide the component type to be added(i.e. part or profile). For more info run help"))
 		},
 	}
 	// attach sub commands
-	addCmd.AddCommand(AddPart(configFile, client, indent))
-	addCmd.AddCommand(AddProfile(configFile, client, indent))
+	addCmd.AddCommand(AddPart(configFile, client))
+	addCmd.AddCommand(AddProfile(configFile, client))
 	return addCmd
 }
 
 // AddPart() handles the sub command for uploading a logical
 // part using the path to a yml file.
-func AddPart(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func AddPart(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	var argNoRollback bool
 	addPartCmd := &cobra.Command{
 		Use:   "part [path]",
 		Short: "Add a part to Software Parts Catalog.",
+		Long: `Add a part to Software Parts Catalog. The part is created first, followed by its aliases and the links to its subparts.
+If one of these steps fails, the steps already applied are rolled back by deleting the created part together with its aliases and links, unless --no-rollback is given.`,
 		// the function to be executed as a setup to the command being ran
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No path provided.")
+				return usageError(errors.New("No path provided."))
 			}
 			return nil
 		},
@@ -65,51 +68,58 @@ func AddPart(configFile *config.ConfigData, client *graph.Client, indent string)
 			// scan the arguments to the command
 			argPartImportPath := args[0]
 			if argPartImportPath != "" {
-				// check if the file is of yaml/yml format
-				if argPartImportPath[len(argPartImportPath)-5:] != ".yaml" && argPartImportPath[len(argPartImportPath)-4:] != ".yml" {
-					return errors.New("error importing part, import path not a yaml file")
-				}
-				// open the file
-				f, err := os.Open(argPartImportPath)
-				if err != nil {
-					return errors.Wrap(err, "error opening file")
-				}
-				defer f.Close()
-				slog.Debug("successfully opened file", slog.String("file:", argPartImportPath))
-				// read the contents of the file
-				data, err := io.ReadAll(f)
+				// read the part data of the yaml/yml file
+				partData, err := readPartFile(argPartImportPath)
 				if err != nil {
-					return errors.Wrapf(err, "error reading file")
-				}
-				var partData yaml.Part
-				// unmarshal all the data of the file into a struct
-				if err = yaml.Unmarshal(data, &partData); err != nil {
-					return errors.Wrapf(err, "error unmarshaling file contents")
+					return err
 				}
 				slog.Debug("adding part")
 				// call the graphql helper for adding a new part
-				createdPart, err := graphql.AddPart(context.Background(), client, partData)
+				createdPart, err := graphql.AddPart(cmd.Context(), client, *partData, nil, !argNoRollback)
 				if err != nil {
 					return errors.Wrapf(err, "error adding part")
 				}
-				// create a indented json output for the path added
-				prettyPart, err := json.MarshalIndent(&createdPart, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
-				}
-				fmt.Printf("Successfully added part from: %s\n", argPartImportPath)
-				fmt.Printf("%s\n", string(prettyPart))
+				printer := newPrinter(cmd, configFile)
+				printer.Info("Successfully added part from: %s", argPartImportPath)
+				return printer.Print(createdPart)
 			}
 
 			return nil
 		},
 	}
+	addPartCmd.Flags().BoolVar(&argNoRollback, "no-rollback", false, "Keep the changes applied to the catalog if adding the part fails")
 	return addPartCmd
 }
 
+// reportPartialMutation() tells the user which mutations of a failed operation
+// were applied to the catalog and which of them were rolled back
+func reportPartialMutation(partialErr *graphql.PartialMutationError) {
+	fmt.Fprintln(os.Stderr, "Applied to the catalog before the failure:")
+	for _, applied := range partialErr.Applied {
+		fmt.Fprintf(os.Stderr, "  %s\n", applied)
+	}
+	if partialErr.RolledBack == nil && partialErr.RollbackErr == nil {
+		fmt.Fprintln(os.Stderr, "Nothing was rolled back.")
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Rolled back:")
+	for _, rolledBack := range partialErr.RolledBack {
+		fmt.Fprintf(os.Stderr, "  %s\n", rolledBack)
+	}
+	if partialErr.RollbackErr != nil {
+		fmt.Fprintf(os.Stderr, "Rollback incomplete: %v\n", partialErr.RollbackErr)
+	}
+}
+
+// struct for the result of adding a profile to a part
+type addProfileResult struct {
+	PartID  string `json:"part_id"`
+	Profile string `json:"profile"`
+}
+
 // AddProfile() handles the upload of a part's profile
 // like license, security and quality using a yml file
-func AddProfile(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func AddProfile(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// create a cobra command
 	addProfileCmd := &cobra.Command{
 		Use:   "profile [path]",
@@ -117,184 +127,118 @@ func AddProfile(configFile *config.ConfigData, client *graph.Client, indent stri
 		// function to be run as a setup for the command
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No path provided.")
+				return usageError(errors.New("No path provided."))
 			}
 			return nil
 		},
 		// function to be run on command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argImportPath := args[0]
-			if argImportPath != "" {
-				// check if the file is of yaml/yml format
-				if argImportPath[len(argImportPath)-5:] != ".yaml" && argImportPath[len(argImportPath)-4:] != ".yml" {
-					return errors.New("error importing profile, import path not a yaml file")
-				}
-				// open the file
-				f, err := os.Open(argImportPath)
-				if err != nil {
-					return errors.Wrapf(err, "error opening file")
-				}
-				defer f.Close()
-				slog.Debug("Successfully opened file", slog.String("file:", argImportPath))
-				// read the file data
-				data, err := io.ReadAll(f)
-				if err != nil {
-					return errors.Wrapf(err, "error reading file")
-				}
-				var profileData yaml.Profile
-				// unmarshal the file data into a struct
-				if err = yaml.Unmarshal(data, &profileData); err != nil {
-					return errors.Wrapf(err, "error unmarshaling file contents")
+			// the profile document is decoded into the struct of its type
+			profileData, document, err := readProfileFile(argImportPath)
+			if err != nil {
+				return err
+			}
+			// check if the part identifier is present
+			if !hasPartIdentifier(profileData.CatalogID, profileData.FVC, profileData.Sha256) {
+				return inputError(errors.New("error adding profile, no part identifier given"))
+			}
+			slog.Debug("adding profile", slog.String("Key", profileData.Profile))
+			printer := newPrinter(cmd, configFile)
+			// parts the profile was added to
+			added := []addProfileResult{}
+			// add the profile if the part id is given
+			if profileData.CatalogID != "" {
+				if err = graphql.AddProfile(cmd.Context(), client, profileData.CatalogID, profileData.Profile, document); err != nil {
+					return errors.Wrapf(err, "error adding profile")
 				}
-				slog.Debug("adding profile", slog.String("Key", profileData.Profile))
-				// switch case for various profile types
-				switch profileData.Profile {
-				case "security":
-					var securityProfile yaml.SecurityProfile
-					// unmarshal the data into a security profile struct
-					if err = yaml.Unmarshal(data, &securityProfile); err != nil {
-						return errors.Wrapf(err, "error unmarshaling security profile")
-					}
-					// marshal the security profile struct into a json
-					jsonSecurityProfile, err := json.Marshal(securityProfile)
-					if err != nil {
-						return errors.Wrapf(err, "error marshaling json")
-					}
-					// check if the part identifier is present
-					if profileData.CatalogID == "" && profileData.FVC == "" && profileData.Sha256 == "" {
-						return errors.New("error adding profile, no part identifier given")
-					}
-					// add the profile if the part id is given
-					if profileData.CatalogID != "" {
-						if err = graphql.AddProfile(context.Background(), client, profileData.CatalogID, profileData.Profile, jsonSecurityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added security profile to %s-%s\n", profileData.Name, profileData.Version)
-					}
-					// add the profile by first getting the part id using the fvc
-					if profileData.FVC != "" {
-						slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", profileData.FVC))
-						uuid, err := graphql.GetPartIDByFVC(context.Background(), client, profileData.FVC)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by fvc")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonSecurityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added security profile to %s-%s\n", profileData.Name, profileData.Version)
-						break
-					}
-					// add the profile by first getting the part id using the sha256
-					if profileData.Sha256 != "" {
-						slog.Debug("retrieving part id by sha256", slog.String("SHA256", profileData.Sha256))
-						uuid, err := graphql.GetPartIDBySha256(context.Background(), client, profileData.Sha256)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by sha256")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonSecurityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added security profile to %s-%s\n", profileData.Name, profileData.Version)
-					}
-				case "licensing":
-					var licensingProfile yaml.LicensingProfile
-					// unmarshal the data into a licensing profile struct
-					if err = yaml.Unmarshal(data, &licensingProfile); err != nil {
-						return errors.Wrapf(err, "error unmarshaling licensing profile")
-					}
-					// marshal the licensing profile struct to json
-					jsonLicensingProfile, err := json.Marshal(licensingProfile)
-					if err != nil {
-						return errors.Wrapf(err, "error marshaling json")
-					}
-					// check if the part identifier is given
-					if profileData.CatalogID == "" && profileData.FVC == "" && profileData.Sha256 == "" {
-						return errors.New("error adding profile, no part identifier given")
-					}
-					// add the profile using the part id
-					if profileData.CatalogID != "" {
-						if err = graphql.AddProfile(context.Background(), client, profileData.CatalogID, profileData.Profile, jsonLicensingProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added licensing profile to %s-%s\n", profileData.Name, profileData.Version)
-					}
-					// add the profile by first getting the part id using the fvc
-					if profileData.FVC != "" {
-						slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", profileData.FVC))
-						uuid, err := graphql.GetPartIDByFVC(context.Background(), client, profileData.FVC)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by fvc")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonLicensingProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added licensing profile to %s-%s\n", profileData.Name, profileData.Version)
-						break
-					}
-					// add the profile by first getting the part id using the sha256
-					if profileData.Sha256 != "" {
-						slog.Debug("retrieving part id by sha256", slog.String("SHA256", profileData.Sha256))
-						uuid, err := graphql.GetPartIDBySha256(context.Background(), client, profileData.Sha256)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by sha256")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonLicensingProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added licensing profile to %s-%s\n", profileData.Name, profileData.Version)
-					}
-				case "quality":
-					var qualityProfile yaml.QualityProfile
-					// unmarshal the data into a quality profile struct
-					if err = yaml.Unmarshal(data, &qualityProfile); err != nil {
-						return errors.Wrapf(err, "error unmarshaling quality profile")
-					}
-					// marshal the quality profile struct to a json
-					jsonQualityProfile, err := json.Marshal(qualityProfile)
-					if err != nil {
-						return errors.Wrapf(err, "error marshaling json")
-					}
-					// cehck if the part identifier is given
-					if profileData.CatalogID == "" && profileData.FVC == "" && profileData.Sha256 == "" {
-						return errors.Wrapf(err, "error adding profile, no part identifier given")
-					}
-					// add the profile by using the part id
-					if profileData.CatalogID != "" {
-						if err = graphql.AddProfile(context.Background(), client, profileData.CatalogID, profileData.Profile, jsonQualityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added quality profile to %s-%s\n", profileData.Name, profileData.Version)
-					}
-					// add the profile by first getting the part id using the fvc
-					if profileData.FVC != "" {
-						slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", profileData.FVC))
-						uuid, err := graphql.GetPartIDByFVC(context.Background(), client, profileData.FVC)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by fvc")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonQualityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added quality profile to %s-%s\n", profileData.Name, profileData.Version)
-						break
-					}
-					// add the profile by first getting the part id using the sha256
-					if profileData.Sha256 != "" {
-						slog.Debug("retrieving part id by sha256", slog.String("SHA256", profileData.Sha256))
-						uuid, err := graphql.GetPartIDBySha256(context.Background(), client, profileData.Sha256)
-						if err != nil {
-							return errors.Wrapf(err, "error retrieving part id by sha256")
-						}
-						if err = graphql.AddProfile(context.Background(), client, uuid.String(), profileData.Profile, jsonQualityProfile); err != nil {
-							return errors.Wrapf(err, "error adding profile")
-						}
-						fmt.Printf("Successfully added quality profile to %s-%s\n", profileData.Name, profileData.Version)
+				printer.Info("Successfully added %s profile to %s-%s", profileData.Profile, profileData.Name, profileData.Version)
+				added = append(added, addProfileResult{PartID: profileData.CatalogID, Profile: profileData.Profile})
+			}
+			// add the profile by first getting the part id using the fvc, or else the sha256
+			if profileData.FVC != "" || profileData.Sha256 != "" {
+				var partID *uuid.UUID
+				if profileData.FVC != "" {
+					slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", profileData.FVC))
+					if partID, err = graphql.GetPartIDByFVC(cmd.Context(), client, profileData.FVC); err != nil {
+						return errors.Wrapf(err, "error retrieving part id by fvc")
+					}
+				} else {
+					slog.Debug("retrieving part id by sha256", slog.String("SHA256", profileData.Sha256))
+					if partID, err = graphql.GetPartIDBySha256(cmd.Context(), client, profileData.Sha256); err != nil {
+						return errors.Wrapf(err, "error retrieving part id by sha256")
 					}
 				}
+				if err = graphql.AddProfile(cmd.Context(), client, partID.String(), profileData.Profile, document); err != nil {
+					return errors.Wrapf(err, "error adding profile")
+				}
+				printer.Info("Successfully added %s profile to %s-%s", profileData.Profile, profileData.Name, profileData.Version)
+				added = append(added, addProfileResult{PartID: partID.String(), Profile: profileData.Profile})
 			}
-
-			return nil
+			return printer.Print(added)
 		},
 	}
 	return addProfileCmd
 }
+
+// readPartFile() reads the part data of a yml file
+func readPartFile(path string) (*yaml.Part, error) {
+	data, err := readYamlFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var partData yaml.Part
+	if err = yaml.Unmarshal(data, &partData); err != nil {
+		return nil, inputError(errors.Wrapf(err, "error decoding %s", path))
+	}
+	return &partData, nil
+}
+
+// readProfileFile() reads the profile of a yml file and gives
+// it along with its document in the json expected by the catalog
+func readProfileFile(path string) (*yaml.Profile, json.RawMessage, error) {
+	data, err := readYamlFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeProfile(data, path)
+}
+
+// decodeProfile() decodes the profile of the yml data of the path
+func decodeProfile(data []byte, path string) (*yaml.Profile, json.RawMessage, error) {
+	var profileData yaml.Profile
+	if err := yaml.Unmarshal(data, &profileData); err != nil {
+		return nil, nil, inputError(errors.Wrapf(err, "error decoding %s", path))
+	}
+	var document interface{}
+	switch profileData.Profile {
+	case "security":
+		document = &yaml.SecurityProfile{}
+	case "licensing":
+		document = &yaml.LicensingProfile{}
+	case "quality":
+		document = &yaml.QualityProfile{}
+	default:
+		return nil, nil, inputError(errors.Errorf("error decoding %s, unknown profile %q", path, profileData.Profile))
+	}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, nil, inputError(errors.Wrapf(err, "error decoding %s profile of %s", profileData.Profile, path))
+	}
+	jsonDocument, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error marshaling json")
+	}
+	return &profileData, jsonDocument, nil
+}
+
+// readYamlFile() reads the contents of a yml file
+func readYamlFile(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml") {
+		return nil, inputError(errors.Errorf("error reading %s, not a yaml file", path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, inputError(errors.Wrapf(err, "error reading %s", path))
+	}
+	return data, nil
+}
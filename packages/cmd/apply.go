@@ -0,0 +1,425 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"wrs/catalog/ccli/packages/config"
+	"wrs/catalog/ccli/packages/graphql"
+	"wrs/catalog/ccli/packages/output"
+	"wrs/catalog/ccli/packages/yaml"
+
+	graph "github.com/hasura/go-graphql-client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// kinds of the documents of a manifest
+const (
+	documentKindPart    = "part"
+	documentKindProfile = "profile"
+)
+
+// statuses of the documents of an apply
+const (
+	applyStatusCreated = "created"
+	applyStatusUpdated = "updated"
+	applyStatusAdded   = "added"
+	applyStatusFailed  = "failed"
+	applyStatusSkipped = "skipped"
+)
+
+// struct for the result of applying a document of a manifest
+type applyResult struct {
+	Document string `json:"document"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	PartID   string `json:"part_id"`
+	Error    string `json:"error,omitempty"`
+}
+
+// manifestDocument is a part or a profile of a manifest
+type manifestDocument struct {
+	// path of the manifest followed by the number of the document in it
+	source  string
+	part    *yaml.Part
+	profile *yaml.Profile
+	// profile document in the json expected by the catalog
+	profileDocument json.RawMessage
+	// indexes of the parts of the manifest the document refers to
+	dependencies []int
+	// part id of the document once it is applied
+	partID string
+	result applyResult
+}
+
+// Apply() applies the parts and profiles of yml manifests
+// to the catalog in the order of their dependencies
+func Apply(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	var argFilenames []string
+	applyCmd := &cobra.Command{
+		Use:   "apply -f [path]",
+		Short: "Apply the parts and profiles of yml manifests to the Software Parts Catalog",
+		Long: `Apply the parts and profiles of yml manifests to the Software Parts Catalog. A manifest
+holds any number of part and profile documents separated by '---', in the format of
+add part and add profile. Documents with a profile key are profiles, all others parts.
+-f takes manifests or directories of manifests and can be repeated.
+
+Parts identified by catalog_id, fvc or sha256 are updated as update does, all others
+are added as add part does. An entry of a composite_list may refer to a part of the
+manifests by one of its aliases, such parts are applied before the parts composed of
+them and the entry is kept as the path of the subpart. Parts identified by catalog_id,
+fvc or sha256 can not have a composite_list since update does not change subparts. A profile without a part identifier is added to the part of the manifests with
+its name and version, after that part is applied.
+
+The result of every document is shown, a document is skipped if a part it refers to
+failed to apply. The command fails if any document failed or was skipped.`,
+		// the function to be executed as a setup to the command being ran
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(argFilenames) == 0 {
+				return usageError(errors.New("No manifest provided, use -f <path>."))
+			}
+			if len(args) > 0 {
+				return usageError(errors.Errorf("unexpected argument %q, manifests are given with -f", args[0]))
+			}
+			return nil
+		},
+		// the function be executed when the command is ran
+		RunE: func(cmd *cobra.Command, args []string) error {
+			documents, err := readManifests(argFilenames)
+			if err != nil {
+				return err
+			}
+			if err = resolveDependencies(documents); err != nil {
+				return err
+			}
+			order, err := applyOrder(documents)
+			if err != nil {
+				return err
+			}
+			printer := newPrinter(cmd, configFile)
+			if !formatIsExplicit(cmd) {
+				printer.Format = output.Table
+			}
+			slog.Debug("applying manifests", slog.Int("Documents", len(documents)))
+
+			results := make([]applyResult, 0, len(order))
+			counts := make(map[string]int)
+			for _, index := range order {
+				document := documents[index]
+				if err = cmd.Context().Err(); err != nil {
+					document.result.Status, document.result.Error = applyStatusFailed, err.Error()
+				} else {
+					applyDocument(cmd, client, document, documents)
+				}
+				if document.result.Error != "" {
+					printer.Info("%s %s %s: %s", document.result.Status, document.result.Kind, document.source, document.result.Error)
+				} else {
+					printer.Info("%s %s %s", document.result.Status, document.result.Kind, document.source)
+				}
+				results = append(results, document.result)
+				counts[document.result.Status]++
+			}
+			if err = printer.Print(results); err != nil {
+				return err
+			}
+			printer.Info("Created %d, updated %d, added %d profiles, failed %d, skipped %d of %d documents",
+				counts[applyStatusCreated], counts[applyStatusUpdated], counts[applyStatusAdded], counts[applyStatusFailed], counts[applyStatusSkipped], len(documents))
+			if err = cmd.Context().Err(); err != nil {
+				return err
+			}
+			if notApplied := counts[applyStatusFailed] + counts[applyStatusSkipped]; notApplied > 0 {
+				return errors.Errorf("%d of %d documents were not applied", notApplied, len(documents))
+			}
+			return nil
+		},
+	}
+	applyCmd.Flags().StringArrayVarP(&argFilenames, "filename", "f", nil, "Manifest or directory of manifests to apply (repeatable)")
+	addSelectionFlags(applyCmd)
+	return applyCmd
+}
+
+// readManifests() reads the documents of the manifests, the manifests of a
+// directory are the yml files directly in it in the order of their names
+func readManifests(paths []string) ([]*manifestDocument, error) {
+	documents := []*manifestDocument{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, inputError(errors.Wrapf(err, "error reading manifest"))
+		}
+		manifests := []string{path}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, inputError(errors.Wrapf(err, "error reading directory %s", path))
+			}
+			manifests = manifests[:0]
+			for _, entry := range entries {
+				if entry.Type().IsRegular() && (strings.HasSuffix(entry.Name(), ".yaml") || strings.HasSuffix(entry.Name(), ".yml")) {
+					manifests = append(manifests, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		for _, manifest := range manifests {
+			data, err := readYamlFile(manifest)
+			if err != nil {
+				return nil, err
+			}
+			manifestDocuments, err := decodeManifest(manifest, data)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, manifestDocuments...)
+		}
+	}
+	if len(documents) == 0 {
+		return nil, inputError(errors.New("error reading manifests, no documents found"))
+	}
+	return documents, nil
+}
+
+// decodeManifest() decodes the part and profile documents of a manifest
+func decodeManifest(path string, data []byte) ([]*manifestDocument, error) {
+	documents := []*manifestDocument{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for number := 1; ; number++ {
+		var fields map[string]interface{}
+		err := decoder.Decode(&fields)
+		if err == io.EOF {
+			return documents, nil
+		}
+		source := fmt.Sprintf("%s#%d", path, number)
+		if err != nil {
+			return nil, inputError(errors.Wrapf(err, "error decoding %s", source))
+		}
+		// empty documents are left out
+		if len(fields) == 0 {
+			continue
+		}
+		// the document is decoded again into the struct of its kind
+		documentData, err := yaml.Marshal(fields)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding %s", source)
+		}
+		document := &manifestDocument{source: source}
+		if _, ok := fields["profile"]; ok {
+			if document.profile, document.profileDocument, err = decodeProfile(documentData, source); err != nil {
+				return nil, err
+			}
+			document.result = applyResult{Document: source, Kind: documentKindProfile, Name: document.profile.Profile}
+			if document.profile.Name != "" {
+				document.result.Name += " " + documentName(document.profile.Name, document.profile.Version)
+			}
+		} else {
+			document.part = new(yaml.Part)
+			if err = yaml.Unmarshal(documentData, document.part); err != nil {
+				return nil, inputError(errors.Wrapf(err, "error decoding %s", source))
+			}
+			document.result = applyResult{Document: source, Kind: documentKindPart, Name: documentName(document.part.Name, document.part.Version)}
+		}
+		documents = append(documents, document)
+	}
+}
+
+// documentName() gives the name of a part or a profile shown in the results
+func documentName(name string, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "-" + version
+}
+
+// hasPartIdentifier() checks if the part is identified by its id, file verification code or sha256
+func hasPartIdentifier(catalogID string, fvc string, sha256 string) bool {
+	return catalogID != "" || fvc != "" || sha256 != ""
+}
+
+// resolveDependencies() sets the parts of the manifests the documents refer to, the
+// parts of the composite list of a part by their aliases and the part of a profile
+// without a part identifier by its name and version
+func resolveDependencies(documents []*manifestDocument) error {
+	aliases := make(map[string]int)
+	for index, document := range documents {
+		if document.part == nil {
+			continue
+		}
+		for _, alias := range document.part.Aliases {
+			if other, ok := aliases[alias]; ok && other != index {
+				return inputError(errors.Errorf("error resolving manifests, alias %s is given by %s and %s", alias, documents[other].source, document.source))
+			}
+			aliases[alias] = index
+		}
+	}
+	for _, document := range documents {
+		if document.part != nil {
+			// update does not change the subparts of a part
+			if hasPartIdentifier(document.part.CatalogID, document.part.FVC, document.part.Sha256) && len(document.part.CompositeList) > 0 {
+				return inputError(errors.Errorf("error resolving %s, composite_list can only be given for new parts but it identifies an existing part", document.source))
+			}
+			for _, entry := range document.part.CompositeList {
+				if index, ok := aliases[entry]; ok {
+					document.dependencies = append(document.dependencies, index)
+				}
+			}
+			continue
+		}
+		profile := document.profile
+		if hasPartIdentifier(profile.CatalogID, profile.FVC, profile.Sha256) {
+			continue
+		}
+		for index, other := range documents {
+			if other.part != nil && other.part.Name == profile.Name && other.part.Version == profile.Version {
+				if len(document.dependencies) > 0 {
+					return inputError(errors.Errorf("error resolving %s, several parts of the manifests are %s", document.source, documentName(profile.Name, profile.Version)))
+				}
+				document.dependencies = append(document.dependencies, index)
+			}
+		}
+		if len(document.dependencies) == 0 {
+			return inputError(errors.Errorf("error resolving %s, no part identifier given and no part of the manifests is %s", document.source, documentName(profile.Name, profile.Version)))
+		}
+	}
+	return nil
+}
+
+// applyOrder() gives the indexes of the documents in the order they are applied,
+// every document after the parts it refers to and otherwise in the order given
+func applyOrder(documents []*manifestDocument) ([]int, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(documents))
+	order := make([]int, 0, len(documents))
+	var visit func(index int) error
+	visit = func(index int) error {
+		switch states[index] {
+		case visited:
+			return nil
+		case visiting:
+			return inputError(errors.Errorf("error resolving manifests, the composite list of %s refers back to it", documents[index].source))
+		}
+		states[index] = visiting
+		for _, dependency := range documents[index].dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		states[index] = visited
+		order = append(order, index)
+		return nil
+	}
+	for index := range documents {
+		if err := visit(index); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// applyDocument() applies the document to the catalog and sets its result,
+// it is skipped if a part it refers to was not applied
+func applyDocument(cmd *cobra.Command, client *graph.Client, document *manifestDocument, documents []*manifestDocument) {
+	for _, dependency := range document.dependencies {
+		if documents[dependency].partID == "" {
+			document.result.Status = applyStatusSkipped
+			document.result.Error = fmt.Sprintf("%s was not applied", documents[dependency].source)
+			return
+		}
+	}
+	var err error
+	if document.part != nil {
+		err = applyPart(cmd, client, document, documents)
+	} else {
+		err = applyProfile(cmd, client, document, documents)
+	}
+	if err != nil {
+		document.result.Status, document.result.Error = applyStatusFailed, err.Error()
+		return
+	}
+	document.result.PartID = document.partID
+}
+
+// applyPart() updates the part of the document if it is identified,
+// or adds it with the parts of the manifests it is composed of
+func applyPart(cmd *cobra.Command, client *graph.Client, document *manifestDocument, documents []*manifestDocument) error {
+	part := *document.part
+	if hasPartIdentifier(part.CatalogID, part.FVC, part.Sha256) {
+		slog.Debug("updating part", slog.String("Document", document.source))
+		updatedPart, err := graphql.UpdatePart(cmd.Context(), client, &part)
+		if err != nil {
+			return errors.Wrapf(err, "error updating part")
+		}
+		document.partID = updatedPart.ID.String()
+		document.result.Status = applyStatusUpdated
+		return nil
+	}
+	// entries naming parts of the manifests by an alias are linked to the ids
+	// of the parts, the entries themselves are kept as the paths of the subparts
+	children := make(map[string]string)
+	for _, dependency := range document.dependencies {
+		for _, alias := range documents[dependency].part.Aliases {
+			children[alias] = documents[dependency].partID
+		}
+	}
+	slog.Debug("adding part", slog.String("Document", document.source))
+	createdPart, err := graphql.AddPart(cmd.Context(), client, part, children, true)
+	if err != nil {
+		return errors.Wrapf(err, "error adding part")
+	}
+	document.partID = createdPart.ID.String()
+	document.result.Status = applyStatusCreated
+	return nil
+}
+
+// applyProfile() adds the profile of the document to the part it identifies,
+// or to the part of the manifests with its name and version
+func applyProfile(cmd *cobra.Command, client *graph.Client, document *manifestDocument, documents []*manifestDocument) error {
+	profile := document.profile
+	partID := profile.CatalogID
+	switch {
+	case partID != "":
+	case profile.FVC != "":
+		slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", profile.FVC))
+		id, err := graphql.GetPartIDByFVC(cmd.Context(), client, profile.FVC)
+		if err != nil {
+			return errors.Wrapf(err, "error retrieving part id by fvc")
+		}
+		partID = id.String()
+	case profile.Sha256 != "":
+		slog.Debug("retrieving part id by sha256", slog.String("SHA256", profile.Sha256))
+		id, err := graphql.GetPartIDBySha256(cmd.Context(), client, profile.Sha256)
+		if err != nil {
+			return errors.Wrapf(err, "error retrieving part id by sha256")
+		}
+		partID = id.String()
+	default:
+		partID = documents[document.dependencies[0]].partID
+	}
+	slog.Debug("adding profile", slog.String("Key", profile.Profile), slog.String("ID", partID))
+	if err := graphql.AddProfile(cmd.Context(), client, partID, profile.Profile, document.profileDocument); err != nil {
+		return errors.Wrapf(err, "error adding profile")
+	}
+	document.partID = partID
+	document.result.Status = applyStatusAdded
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// manifest of a release with a composite part, its subparts and a profile
+const releaseManifest = `
+name: sdk
+version: "2.0"
+composite_list:
+  - zlib-1.3
+  - openssl-3.0
+  - 0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f
+---
+profile: licensing
+name: zlib
+version: "1.3"
+copyrights: [Jean-loup Gailly and Mark Adler]
+---
+---
+name: zlib
+version: "1.3"
+aliases: [zlib-1.3]
+---
+name: openssl
+version: "3.0"
+aliases: [openssl-3.0]
+composite_list: [zlib-1.3]
+---
+catalog_id: 1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f
+description: updated
+---
+profile: security
+sha256: abc
+cve_list: []
+`
+
+// TestDecodeManifest checks the kinds and sources of the documents of a manifest
+func TestDecodeManifest(tester *testing.T) {
+	documents, err := decodeManifest("release.yml", []byte(releaseManifest))
+	if err != nil {
+		tester.Fatal("failed to decode manifest", err)
+	}
+	expected := []applyResult{
+		{Document: "release.yml#1", Kind: documentKindPart, Name: "sdk-2.0"},
+		{Document: "release.yml#2", Kind: documentKindProfile, Name: "licensing zlib-1.3"},
+		// the empty third document is left out
+		{Document: "release.yml#4", Kind: documentKindPart, Name: "zlib-1.3"},
+		{Document: "release.yml#5", Kind: documentKindPart, Name: "openssl-3.0"},
+		{Document: "release.yml#6", Kind: documentKindPart, Name: ""},
+		{Document: "release.yml#7", Kind: documentKindProfile, Name: "security"},
+	}
+	var results []applyResult
+	for _, document := range documents {
+		results = append(results, document.result)
+	}
+	if !reflect.DeepEqual(results, expected) {
+		tester.Errorf("Expected documents %v but got %v", expected, results)
+	}
+	if documents[0].part.CompositeList[0] != "zlib-1.3" {
+		tester.Errorf("Expected the composite list to be decoded but got %v", documents[0].part.CompositeList)
+	}
+	var licensing map[string]interface{}
+	if err = json.Unmarshal(documents[1].profileDocument, &licensing); err != nil || licensing["copyrights"] == nil {
+		tester.Errorf("Expected the json document of the licensing profile but got %s", documents[1].profileDocument)
+	}
+}
+
+// TestDecodeManifestErrors checks that invalid documents are input errors
+func TestDecodeManifestErrors(tester *testing.T) {
+	for name, manifest := range map[string]string{
+		"malformed yaml":  "name: [sdk\n",
+		"unknown profile": "name: sdk\n---\nprofile: nope\n",
+		"list document":   "- name: sdk\n",
+	} {
+		_, err := decodeManifest("release.yml", []byte(manifest))
+		if !isInputError(err) {
+			tester.Errorf("%s: expected an input error but got %v", name, err)
+		}
+	}
+}
+
+// TestApplyOrder checks that parts are applied after the parts they refer to
+func TestApplyOrder(tester *testing.T) {
+	documents, err := decodeManifest("release.yml", []byte(releaseManifest))
+	if err != nil {
+		tester.Fatal("failed to decode manifest", err)
+	}
+	if err = resolveDependencies(documents); err != nil {
+		tester.Fatal("failed to resolve dependencies", err)
+	}
+	expectedDependencies := [][]int{{2, 3}, {2}, nil, {2}, nil, nil}
+	for i, document := range documents {
+		if !reflect.DeepEqual(document.dependencies, expectedDependencies[i]) {
+			tester.Errorf("Expected %s to depend on %v but got %v", document.source, expectedDependencies[i], document.dependencies)
+		}
+	}
+	order, err := applyOrder(documents)
+	if err != nil {
+		tester.Fatal("failed to order documents", err)
+	}
+	// zlib, openssl and sdk are applied first, the others in the order given
+	if expected := []int{2, 3, 0, 1, 4, 5}; !reflect.DeepEqual(order, expected) {
+		tester.Errorf("Expected the order %v but got %v", expected, order)
+	}
+}
+
+// TestResolveDependenciesErrors checks the manifests which can not be applied
+func TestResolveDependenciesErrors(tester *testing.T) {
+	tests := map[string]string{
+		"duplicate alias":             "name: a\naliases: [x]\n---\nname: b\naliases: [x]\n",
+		"profile without part":        "profile: security\nname: nope\ncve_list: []\n",
+		"profile of several parts":    "name: a\n---\nname: a\n---\nprofile: security\nname: a\ncve_list: []\n",
+		"composite of existing parts": "name: a\naliases: [x]\n---\ncatalog_id: 1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f\ncomposite_list: [x]\n",
+	}
+	for name, manifest := range tests {
+		documents, err := decodeManifest("release.yml", []byte(manifest))
+		if err != nil {
+			tester.Fatalf("%s: failed to decode manifest: %v", name, err)
+		}
+		if err = resolveDependencies(documents); !isInputError(err) {
+			tester.Errorf("%s: expected an input error but got %v", name, err)
+		}
+	}
+}
+
+// TestApplyOrderCycle checks that composite lists referring back to a part are rejected
+func TestApplyOrderCycle(tester *testing.T) {
+	manifest := "name: a\naliases: [x]\ncomposite_list: [z]\n---\nname: b\naliases: [y]\ncomposite_list: [x]\n---\nname: c\naliases: [z]\ncomposite_list: [y]\n"
+	documents, err := decodeManifest("release.yml", []byte(manifest))
+	if err != nil {
+		tester.Fatal("failed to decode manifest", err)
+	}
+	if err = resolveDependencies(documents); err != nil {
+		tester.Fatal("failed to resolve dependencies", err)
+	}
+	if _, err = applyOrder(documents); !isInputError(err) {
+		tester.Errorf("Expected an input error for the cycle but got %v", err)
+	}
+}
+
+// isInputError() checks if the error is an error of the input of a command
+func isInputError(err error) bool {
+	var cliErr *Error
+	return errors.As(err, &cliErr) && cliErr.Kind == ErrorInput
+}
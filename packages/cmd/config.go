@@ -0,0 +1,147 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"wrs/catalog/ccli/packages/config"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Config() handles the commands for inspecting the ccli configuration
+func Config(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for config
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the ccli configuration",
+		// the command does not contact the catalog server
+		Annotations: map[string]string{AnnotationOffline: "true"},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usageError(errors.New("Please provide the config subcommand(i.e. path, get-contexts, current-context, use-context). For more info run help"))
+		},
+	}
+	// add sub commands to config
+	configCmd.AddCommand(ConfigPath(configFile))
+	configCmd.AddCommand(ConfigGetContexts(configFile))
+	configCmd.AddCommand(ConfigCurrentContext(configFile))
+	configCmd.AddCommand(ConfigUseContext(configFile))
+	return configCmd
+}
+
+// ConfigPath() prints the path of the configuration file in use
+func ConfigPath(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for config path
+	configPathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the configuration file in use",
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile.Path == "" {
+				return configError(errors.New("no configuration file in use, settings are taken from the environment and flags"))
+			}
+			// report the absolute path if it can be resolved
+			path, err := filepath.Abs(configFile.Path)
+			if err != nil {
+				path = configFile.Path
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+	return configPathCmd
+}
+
+// ConfigGetContexts() lists the contexts of the configuration file
+func ConfigGetContexts(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for listing contexts
+	configGetContextsCmd := &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List the contexts of the configuration file",
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(configFile.Contexts) == 0 {
+				fmt.Println("No contexts found")
+				return nil
+			}
+			// print the contexts as a table marking the one in use
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(writer, "CURRENT\tNAME\tSERVER")
+			for _, name := range configFile.ContextNames() {
+				current := ""
+				if strings.EqualFold(name, configFile.Context) {
+					current = "*"
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", current, name, configFile.Contexts[name].ServerAddr)
+			}
+			return writer.Flush()
+		},
+	}
+	return configGetContextsCmd
+}
+
+// ConfigCurrentContext() prints the current context of the configuration file
+func ConfigCurrentContext(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for printing the current context
+	configCurrentContextCmd := &cobra.Command{
+		Use:   "current-context",
+		Short: "Print the current context of the configuration file",
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile.CurrentContext == "" {
+				return configError(errors.New("current context is not set"))
+			}
+			fmt.Println(configFile.CurrentContext)
+			return nil
+		},
+	}
+	return configCurrentContextCmd
+}
+
+// ConfigUseContext() sets the current context of the configuration file
+func ConfigUseContext(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for switching the current context
+	configUseContextCmd := &cobra.Command{
+		Use:   "use-context [context name]",
+		Short: "Set the current context of the configuration file",
+		// function to be run as setup for command execution
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return usageError(errors.New("No context name provided."))
+			}
+			return nil
+		},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argContext := args[0]
+			// check if the context is present in the configuration file, context
+			// names are case insensitive like all the other configuration keys
+			if _, ok := configFile.Contexts[strings.ToLower(argContext)]; !ok {
+				return errors.Errorf("context %q not found in configuration file", argContext)
+			}
+			slog.Debug("switching context", slog.String("Context", argContext), slog.String("Path", configFile.Path))
+			if err := config.SetCurrentContext(configFile.Path, argContext); err != nil {
+				return errors.Wrapf(err, "error switching context")
+			}
+			fmt.Fprintf(os.Stderr, "Switched to context %q\n", argContext)
+			return nil
+		},
+	}
+	return configUseContextCmd
+}
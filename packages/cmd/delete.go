@@ -12,8 +12,6 @@
 package cmd
 
 import (
-	"context"
-	"fmt"
 	"log/slog"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
@@ -24,11 +22,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// struct for the result of deleting a part
+type deleteResult struct {
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted"`
+}
+
 // Delete() removes a given part from the catalog using the
 // part id and takes the flag for recursive and forced delete
 // recursive and forced delete are currently disabled to
 // reduce delete times and protect wrongfull deletion of files
-func Delete(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Delete(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	var argForcedMode bool
 	var argRecursiveMode bool
 	// cobra command for delete
@@ -38,7 +42,7 @@ func Delete(configFile *config.ConfigData, client *graph.Client, indent string)
 		// function to run as a setup on command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No part id provided.")
+				return usageError(errors.New("No part id provided."))
 			}
 			return nil
 		},
@@ -47,15 +51,17 @@ func Delete(configFile *config.ConfigData, client *graph.Client, indent string)
 			// check if the part id is provided as an argument
 			argPartID := args[0]
 			if argPartID == "" {
-				return errors.New("error deleting part, delete subcommand usage: ./ccli delete <catalog_id>")
+				return usageError(errors.New("error deleting part, delete subcommand usage: ./ccli delete <catalog_id>"))
 			}
 			// delete the part if the part id is present
 			if argPartID != "" {
 				slog.Debug("deleting part", slog.String("ID", argPartID))
-				if err := graphql.DeletePart(context.Background(), client, argPartID, argRecursiveMode, argForcedMode); err != nil {
+				if err := graphql.DeletePart(cmd.Context(), client, argPartID, argRecursiveMode, argForcedMode); err != nil {
 					return errors.Wrapf(err, "error deleting part from catalog")
 				}
-				fmt.Printf("Successfully deleted id: %s from catalog\n", argPartID)
+				printer := newPrinter(cmd, configFile)
+				printer.Info("Successfully deleted id: %s from catalog", argPartID)
+				return printer.Print(deleteResult{ID: argPartID, Deleted: true})
 			}
 			return nil
 		},
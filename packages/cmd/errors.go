@@ -0,0 +1,205 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"wrs/catalog/ccli/packages/graphql"
+	"wrs/catalog/ccli/packages/output"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ErrorKind classifies the failures of ccli, every kind has its own exit code
+type ErrorKind string
+
+const (
+	ErrorGeneral      ErrorKind = "error"
+	ErrorUsage        ErrorKind = "usage"
+	ErrorConfig       ErrorKind = "config"
+	ErrorUnreachable  ErrorKind = "unreachable"
+	ErrorUnauthorized ErrorKind = "unauthorized"
+	ErrorGraphQL      ErrorKind = "graphql"
+	ErrorNotFound     ErrorKind = "not_found"
+	ErrorInput        ErrorKind = "invalid_input"
+	ErrorTimeout      ErrorKind = "timeout"
+	ErrorInterrupted  ErrorKind = "interrupted"
+)
+
+// exit codes of the error kinds, documented in the manual
+var exitCodes = map[ErrorKind]int{
+	ErrorGeneral:      1,
+	ErrorUsage:        2,
+	ErrorConfig:       3,
+	ErrorUnreachable:  4,
+	ErrorUnauthorized: 5,
+	ErrorGraphQL:      6,
+	ErrorNotFound:     7,
+	ErrorInput:        8,
+	ErrorTimeout:      124,
+	ErrorInterrupted:  130,
+}
+
+// ExitCode() gives the exit code of the error kind
+func (kind ErrorKind) ExitCode() int {
+	if code, ok := exitCodes[kind]; ok {
+		return code
+	}
+	return exitCodes[ErrorGeneral]
+}
+
+// Error is an error of a command together with its kind
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap gives the classified error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError() classifies the error as the given kind, nil stays nil
+func newError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// usageError() classifies the error as a wrong invocation of a command
+func usageError(err error) error {
+	return newError(ErrorUsage, err)
+}
+
+// configError() classifies the error as an invalid or missing configuration
+func configError(err error) error {
+	return newError(ErrorConfig, err)
+}
+
+// inputError() classifies the error as an unreadable or invalid input file
+func inputError(err error) error {
+	return newError(ErrorInput, err)
+}
+
+// Classify() gives the kind of the error
+func Classify(err error) ErrorKind {
+	var cmdErr *Error
+	var requestErr *graphql.RequestError
+	var graphQLErr *graphql.GraphQLError
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	// cancellation is checked first since it also fails the requests
+	case errors.Is(err, context.Canceled):
+		return ErrorInterrupted
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.As(err, &cmdErr):
+		return cmdErr.Kind
+	case errors.Is(err, graphql.ErrPartNotFound), errors.Is(err, graphql.ErrArchiveNotFound):
+		return ErrorNotFound
+	case errors.As(err, &requestErr):
+		switch {
+		case requestErr.StatusCode == http.StatusUnauthorized || requestErr.StatusCode == http.StatusForbidden:
+			return ErrorUnauthorized
+		case requestErr.StatusCode == 0 || requestErr.StatusCode >= 500:
+			return ErrorUnreachable
+		}
+		return ErrorGraphQL
+	case errors.As(err, &graphQLErr):
+		return ErrorGraphQL
+	case errors.As(err, &netErr):
+		return ErrorUnreachable
+	case errors.As(err, &pathErr):
+		return ErrorInput
+	}
+	return ErrorGeneral
+}
+
+// struct for the json error envelope written to stderr with --format json
+type errorEnvelope struct {
+	Error errorReport `json:"error"`
+}
+
+type errorReport struct {
+	Kind     ErrorKind `json:"kind"`
+	ExitCode int       `json:"exit_code"`
+	Message  string    `json:"message"`
+	// http status of a failed catalog request
+	StatusCode int `json:"status_code,omitempty"`
+	// graphql errors of the catalog with their path and extensions
+	Errors []graphql.ErrorDetail `json:"errors,omitempty"`
+	// mutations applied and rolled back before a multi step operation failed
+	Applied    []string `json:"applied,omitempty"`
+	RolledBack []string `json:"rolled_back,omitempty"`
+}
+
+// Execute() runs the root command and gives the exit code. A failure is
+// written to stderr, as a json envelope if the json output format is selected.
+func Execute(rootCmd *cobra.Command) int {
+	executedCmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return 0
+	}
+	slog.Error("Error executing command", slog.Any("error", err))
+	// unknown sub commands are reported by cobra without a typed error
+	if strings.HasPrefix(err.Error(), "unknown command") {
+		err = usageError(err)
+	}
+	kind := Classify(err)
+	report := errorReport{Kind: kind, ExitCode: kind.ExitCode(), Message: err.Error()}
+	var requestErr *graphql.RequestError
+	if errors.As(err, &requestErr) {
+		report.StatusCode = requestErr.StatusCode
+	}
+	var graphQLErr *graphql.GraphQLError
+	if errors.As(err, &graphQLErr) {
+		report.Errors = graphQLErr.Errors
+	}
+	var partialErr *graphql.PartialMutationError
+	if errors.As(err, &partialErr) {
+		report.Applied = partialErr.Applied
+		report.RolledBack = partialErr.RolledBack
+	}
+	// the envelope is only written if json output is asked for explicitly, the format
+	// is read from viper since the configuration may have failed to load
+	if format, _ := output.ParseFormat(viper.GetString("format")); formatIsExplicit(rootCmd) && format == output.JSON {
+		printer := output.New(output.JSON, "  ")
+		printer.Out = os.Stderr
+		if printer.Print(errorEnvelope{Error: report}) == nil {
+			return report.ExitCode
+		}
+	}
+	if partialErr != nil {
+		reportPartialMutation(partialErr)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if kind == ErrorUsage {
+		fmt.Fprint(os.Stderr, executedCmd.UsageString())
+	}
+	return report.ExitCode
+}
@@ -24,22 +24,33 @@ func Example() *cobra.Command {
 	exampleCmd := &cobra.Command{
 		Use:   "examples",
 		Short: "Ccli is used to interact with the Software Parts Catalog.",
+		// the command does not contact the catalog server
+		Annotations: map[string]string{AnnotationOffline: "true"},
 		// function to be run on command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// list of all the ccli examples that can be executed
 			exampleString :=
 				`	$ ccli add part openssl-1.1.1n.yml
 	$ ccli add profile profile_openssl-1.1.1n.yml
+	$ ccli apply -f release.yml
 	$ ccli query "{part(id:\"aR25sd-V8dDvs2-p3Gfae\"){file_verification_code}}"
 	$ ccli export part id sdl3ga-naTs42g5-rbow2A -o file.yml
 	$ ccli export template security -o file.yml
 	$ ccli update openssl-1.1.1n.v4.yml
 	$ ccli upload openssl-1.1.1n.tar.gz
+	$ ccli upload -r downloads/ --include '*.tar.gz' --parallel 8
+	$ ccli upload openssl-1.1.1n.tar.gz --part openssl.yml --profile security.yml
 	$ ccli find part busybox
+	$ ccli find part openssl --method fuzzy --license Apache-2.0 --limit 20
+	$ ccli find archive openssl-1.1.1n.tar.gz
 	$ ccli find sha256 2493347f59c03...
+	$ ccli find file openssl-1.1.1n.tar.gz
+	$ ccli hash openssl-1.1.1n.tar.gz
 	$ ccli find profile security werS12-da54FaSff-9U2aef
 	$ ccli delete adjb23-A4D3faTa-d95Xufs
-	$ ccli ping`
+	$ ccli ping
+	$ ccli login --token <token>
+	$ ccli config use-context production`
 			fmt.Printf("%s\n", exampleString)
 			return nil
 		},
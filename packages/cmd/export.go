@@ -12,7 +12,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -27,7 +26,7 @@ import (
 
 // Export() handles getting a part or a template and
 // saving it out to a file on the given path
-func Export(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Export(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	var output string
 	// cobra command for export
 	exportCmd := &cobra.Command{
@@ -35,39 +34,39 @@ func Export(configFile *config.ConfigData, client *graph.Client, indent string)
 		Short: "Export a component based on the subcommands to a file",
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide the export subcommand(part or template). For more info run help")
+			return usageError(errors.New("Please provide the export subcommand(part or template). For more info run help"))
 		},
 	}
 	// add a persistent flag for output file
 	exportCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Path to the output file")
 	// add subcommands for export
-	exportCmd.AddCommand(ExportPart(configFile, client, indent))
-	exportCmd.AddCommand(ExportTemplate(configFile, client, indent))
+	exportCmd.AddCommand(ExportPart(configFile, client))
+	exportCmd.AddCommand(ExportTemplate(configFile, client))
 	return exportCmd
 }
 
 // ExportPart() is a sub command and handles the download
 // of part data and writing to a file on a given path
-func ExportPart(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportPart(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for exporting part
 	exportPartCmd := &cobra.Command{
 		Use:   "part",
 		Short: "Export a part from the Software Parts Catalog",
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide the export part subcommand(i.e. id, sha256, fvc). For more info run help")
+			return usageError(errors.New("Please provide the export part subcommand(i.e. id, sha256, fvc). For more info run help"))
 		},
 	}
 	// add sub commands for part export based on search parameter
-	exportPartCmd.AddCommand(ExportPartId(configFile, client, indent))
-	exportPartCmd.AddCommand(ExportPartSha(configFile, client, indent))
-	exportPartCmd.AddCommand(ExportPartFvc(configFile, client, indent))
+	exportPartCmd.AddCommand(ExportPartId(configFile, client))
+	exportPartCmd.AddCommand(ExportPartSha(configFile, client))
+	exportPartCmd.AddCommand(ExportPartFvc(configFile, client))
 
 	return exportPartCmd
 }
 
 // ExportPartId() gets the part based on part id
-func ExportPartId(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportPartId(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for exporting part based on id
 	exportPartIdCmd := &cobra.Command{
 		Use:   "id [part id] [-o] [export path]",
@@ -75,7 +74,7 @@ func ExportPartId(configFile *config.ConfigData, client *graph.Client, indent st
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No part id provided.")
+				return usageError(errors.New("No part id provided."))
 			}
 			return nil
 		},
@@ -83,13 +82,13 @@ func ExportPartId(configFile *config.ConfigData, client *graph.Client, indent st
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			argPartID := args[0]
 			// get the part data using the part id
 			if argPartID != "" {
 				slog.Debug("retrieving part by id", slog.String("ID", argPartID))
-				part, err := graphql.GetPartByID(context.Background(), client, argPartID)
+				part, err := graphql.GetPartByID(cmd.Context(), client, argPartID)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving part")
 				}
@@ -107,7 +106,7 @@ func ExportPartId(configFile *config.ConfigData, client *graph.Client, indent st
 }
 
 // ExportPartSha() gets the part based on part sha256
-func ExportPartSha(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportPartSha(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for export using sha256
 	exportPartShaCmd := &cobra.Command{
 		Use:   "sha256 [sha256] [-o] [export path]",
@@ -115,7 +114,7 @@ func ExportPartSha(configFile *config.ConfigData, client *graph.Client, indent s
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No part sha256 provided.")
+				return usageError(errors.New("No part sha256 provided."))
 			}
 			return nil
 		},
@@ -123,13 +122,13 @@ func ExportPartSha(configFile *config.ConfigData, client *graph.Client, indent s
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			argSHA256 := args[0]
 			if argSHA256 != "" {
 				// get the part data using sha256
 				slog.Debug("retrieving part by sha256", slog.String("SHA256", argSHA256))
-				part, err := graphql.GetPartBySHA256(context.Background(), client, argSHA256)
+				part, err := graphql.GetPartBySHA256(cmd.Context(), client, argSHA256)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving part")
 				}
@@ -147,7 +146,7 @@ func ExportPartSha(configFile *config.ConfigData, client *graph.Client, indent s
 }
 
 // ExportPartFvc() gets the part based on part file verification code
-func ExportPartFvc(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportPartFvc(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for export using fvc
 	exportPartFvcCmd := &cobra.Command{
 		Use:   "fvc [fvc] [-o] [export path]",
@@ -155,7 +154,7 @@ func ExportPartFvc(configFile *config.ConfigData, client *graph.Client, indent s
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No part file verification code provided.")
+				return usageError(errors.New("No part file verification code provided."))
 			}
 			return nil
 		},
@@ -163,13 +162,13 @@ func ExportPartFvc(configFile *config.ConfigData, client *graph.Client, indent s
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			argFVC := args[0]
 			if argFVC != "" {
 				// get the part data using file verification code
 				slog.Debug("retrieving part by file verification code", slog.String("File Verification Code", argFVC))
-				part, err := graphql.GetPartByFVC(context.Background(), client, argFVC)
+				part, err := graphql.GetPartByFVC(cmd.Context(), client, argFVC)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving part")
 				}
@@ -187,27 +186,29 @@ func ExportPartFvc(configFile *config.ConfigData, client *graph.Client, indent s
 
 // ExportTemplate() handles getting out a template for various part/profile
 // data into a file on the given path
-func ExportTemplate(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportTemplate(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for exporting template
 	exportTemplateCmd := &cobra.Command{
 		Use:   "template [-o] [export path]",
 		Short: "Export a template to a given file",
+		// the command does not contact the catalog server
+		Annotations: map[string]string{AnnotationOffline: "true"},
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide a the find parameter. For more info run help")
+			return usageError(errors.New("Please provide a the find parameter. For more info run help"))
 		},
 	}
 	// add sub commands for template export
-	exportTemplateCmd.AddCommand(ExportTemplatePart(configFile, client, indent))
-	exportTemplateCmd.AddCommand(ExportTemplateSecurity(configFile, client, indent))
-	exportTemplateCmd.AddCommand(ExportTemplateQuality(configFile, client, indent))
-	exportTemplateCmd.AddCommand(ExportTemplateLicense(configFile, client, indent))
+	exportTemplateCmd.AddCommand(ExportTemplatePart(configFile, client))
+	exportTemplateCmd.AddCommand(ExportTemplateSecurity(configFile, client))
+	exportTemplateCmd.AddCommand(ExportTemplateQuality(configFile, client))
+	exportTemplateCmd.AddCommand(ExportTemplateLicense(configFile, client))
 
 	return exportTemplateCmd
 }
 
 // ExportTemplatePart() handles the template for a part
-func ExportTemplatePart(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportTemplatePart(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for exporting part template
 	exportTemplatePartCmd := &cobra.Command{
 		Use:   "part [-o] [export path]",
@@ -216,7 +217,7 @@ func ExportTemplatePart(configFile *config.ConfigData, client *graph.Client, ind
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			// create a new part template
 			yamlPart := new(yaml.Part)
@@ -237,7 +238,7 @@ func ExportTemplatePart(configFile *config.ConfigData, client *graph.Client, ind
 			if err != nil {
 				return errors.Wrapf(err, "error writing template to file")
 			}
-			fmt.Printf("Part template successfully output to: %s\n", argExportPath)
+			fmt.Fprintf(os.Stderr, "Part template successfully output to: %s\n", argExportPath)
 			return nil
 		},
 	}
@@ -245,7 +246,7 @@ func ExportTemplatePart(configFile *config.ConfigData, client *graph.Client, ind
 }
 
 // ExportTemplateSecurity() handles the template for a security profile
-func ExportTemplateSecurity(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportTemplateSecurity(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	//cobra command for exporting security template
 	exportTemplateSecurityCmd := &cobra.Command{
 		Use:   "security [-o] [export path]",
@@ -254,7 +255,7 @@ func ExportTemplateSecurity(configFile *config.ConfigData, client *graph.Client,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			// create a new profile template
 			yamlProfile := new(yaml.Profile)
@@ -289,7 +290,7 @@ func ExportTemplateSecurity(configFile *config.ConfigData, client *graph.Client,
 			if err != nil {
 				return errors.Wrapf(err, "error writing template to file")
 			}
-			fmt.Printf("Profile template successfully output to: %s\n", argExportPath)
+			fmt.Fprintf(os.Stderr, "Profile template successfully output to: %s\n", argExportPath)
 			return nil
 		},
 	}
@@ -297,7 +298,7 @@ func ExportTemplateSecurity(configFile *config.ConfigData, client *graph.Client,
 }
 
 // ExportTemplateQuality() handles the template for a quality profile
-func ExportTemplateQuality(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportTemplateQuality(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	exportTemplateQualityCmd := &cobra.Command{
 		Use:   "quality [-o] [export path]",
 		Short: "Export a quality template",
@@ -305,7 +306,7 @@ func ExportTemplateQuality(configFile *config.ConfigData, client *graph.Client,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			// create a new profile template
 			yamlProfile := new(yaml.Profile)
@@ -340,7 +341,7 @@ func ExportTemplateQuality(configFile *config.ConfigData, client *graph.Client,
 			if err != nil {
 				return errors.Wrapf(err, "error writing template to file")
 			}
-			fmt.Printf("Profile template successfully output to: %s\n", argExportPath)
+			fmt.Fprintf(os.Stderr, "Profile template successfully output to: %s\n", argExportPath)
 			return nil
 		},
 	}
@@ -348,7 +349,7 @@ func ExportTemplateQuality(configFile *config.ConfigData, client *graph.Client,
 }
 
 // ExportTemplateLicense() handles the template for a license profile
-func ExportTemplateLicense(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func ExportTemplateLicense(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	exportTemplateLicenseCmd := &cobra.Command{
 		Use:   "license [-o] [export path]",
 		Short: "Export a license template",
@@ -356,7 +357,7 @@ func ExportTemplateLicense(configFile *config.ConfigData, client *graph.Client,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argExportPath, _ := cmd.Flags().GetString("output")
 			if argExportPath == "" {
-				return errors.New("Output path for exporting is not provided")
+				return usageError(errors.New("Output path for exporting is not provided"))
 			}
 			// create a new profile template
 			yamlProfile := new(yaml.Profile)
@@ -391,7 +392,7 @@ func ExportTemplateLicense(configFile *config.ConfigData, client *graph.Client,
 			if err != nil {
 				return errors.Wrapf(err, "error writing template to file")
 			}
-			fmt.Printf("Profile template successfully output to: %s\n", argExportPath)
+			fmt.Fprintf(os.Stderr, "Profile template successfully output to: %s\n", argExportPath)
 			return nil
 		},
 	}
@@ -422,6 +423,6 @@ func ExportHelper(part *graphql.Part, argExportPath string) error {
 	if err != nil {
 		return errors.Wrapf(err, "error writing part to yaml file")
 	}
-	fmt.Printf("Part successfully exported to path: %s\n", argExportPath)
+	fmt.Fprintf(os.Stderr, "Part successfully exported to path: %s\n", argExportPath)
 	return nil
 }
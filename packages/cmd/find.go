@@ -12,76 +12,326 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
+	"encoding/base64"
 	"log/slog"
-	"os"
+	"strconv"
+	"strings"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
+	"wrs/catalog/ccli/packages/output"
 
+	"github.com/google/uuid"
 	graph "github.com/hasura/go-graphql-client"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 // Find() handles the command for getting a part based on various aspects.
-func Find(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Find(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for file
 	findCmd := &cobra.Command{
 		Use:   "find",
-		Short: "Find a part from the Software Parts Catalog based on the find parameters like fvc, sha256, part query, part id.",
+		Short: "Find a part from the Software Parts Catalog based on the find parameters like fvc, sha256, part query, part id or a local file, or the archives of parts.",
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide the find parameter. For more info run help")
+			return usageError(errors.New("Please provide the find parameter. For more info run help"))
 		},
 	}
 	// add sub commands to find
-	findCmd.AddCommand(FindPart(configFile, client, indent))
-	findCmd.AddCommand(FindId(configFile, client, indent))
+	findCmd.AddCommand(FindPart(configFile, client))
+	findCmd.AddCommand(FindArchive(configFile, client))
+	findCmd.AddCommand(FindId(configFile, client))
 	findCmd.AddCommand(FindSha(configFile, client))
 	findCmd.AddCommand(FindFvc(configFile, client))
-	findCmd.AddCommand(FindProfile(configFile, client, indent))
+	findCmd.AddCommand(FindFile(configFile, client))
+	findCmd.AddCommand(FindProfile(configFile, client))
+	addSelectionFlags(findCmd)
 	return findCmd
 }
 
+// struct for the result of finding a part id
+type partIDResult struct {
+	PartID string `json:"part_id"`
+}
+
+// struct for a row of the part search results in table and csv output,
+// with a row for every archive of the part if archives are shown
+type searchResultRow struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	ID         string `json:"id"`
+	License    string `json:"license"`
+	Archive    string `json:"archive,omitempty"`
+	Sha256     string `json:"sha256,omitempty"`
+	InsertDate string `json:"insert_date,omitempty"`
+}
+
+// struct for a part found together with its archives
+type partWithArchives struct {
+	graphql.Part
+	Archives []partArchive `json:"archives"`
+}
+
+// struct for an archive of a part, leaving out the part itself
+type partArchive struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Sha256     string `json:"sha256"`
+	Sha1       string `json:"sha1"`
+	Md5        string `json:"md5"`
+	InsertDate string `json:"insert_date"`
+}
+
+// struct for an archive found by one of its hashes, together with the part
+// it belongs to if the catalog assigned one
+type archiveWithPart struct {
+	partArchive
+	PartID string        `json:"part_id,omitempty"`
+	Part   *graphql.Part `json:"part,omitempty"`
+}
+
+// newPartArchive() gives the archive leaving out its part
+func newPartArchive(archive graphql.Archive) partArchive {
+	return partArchive{Name: archive.Name, Size: archive.Size, Sha256: archive.Sha256, Sha1: archive.Sha1, Md5: archive.Md5, InsertDate: archive.InsertDate}
+}
+
+// struct for a row of the archive search results in table and csv output
+type archiveResultRow struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Sha256     string `json:"sha256"`
+	InsertDate string `json:"insert_date"`
+	Part       string `json:"part"`
+	PartID     string `json:"part_id"`
+}
+
 // FindPart() handles finding a part based on a search query/part name
-func FindPart(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func FindPart(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	var options graphql.SearchOptions
+	var withArchives bool
 	findPartCmd := &cobra.Command{
 		Use:   "part [search query]",
 		Short: "Find a part using the name(i.e. search query)",
+		Long: `Find parts using the name(i.e. search query). The parts found are sorted by name,
+version and id and shown as a table of name, version, id and license unless an
+output format is selected. With --with-archives the archives found for every part
+are shown as well. Results are paged with --limit, the next page is fetched with
+the --cursor printed after the results or with --offset.`,
 		// function to be run as a setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return errors.New("No part name or search query provided.")
+				return usageError(errors.New("No part name or search query provided."))
 			}
-			return nil
+			return checkSearchFlags(cmd, &options)
 		},
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argSearchQuery := args[0]
 			if argSearchQuery != "" {
 				// search the catalog for the part using the search query
-				slog.Debug("executing part search", slog.String("Query", argSearchQuery))
-				response, err := graphql.Search(context.Background(), client, argSearchQuery)
+				slog.Debug("executing part search", slog.String("Query", argSearchQuery), slog.String("Method", options.Method), slog.Int("Limit", options.Limit), slog.Int("Offset", options.Offset))
+				page, err := graphql.Search(cmd.Context(), client, argSearchQuery, options)
 				if err != nil {
 					return errors.Wrapf(err, "error searching for part")
 				}
-				// marshal the response data into a json
-				prettyJson, err := json.MarshalIndent(response, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
+				var result interface{} = page.Parts
+				if withArchives {
+					parts := make([]partWithArchives, len(page.Parts))
+					for i, part := range page.Parts {
+						parts[i] = partWithArchives{Part: part, Archives: []partArchive{}}
+						for _, archive := range page.Archives[part.ID] {
+							parts[i].Archives = append(parts[i].Archives, newPartArchive(archive))
+						}
+					}
+					result = parts
+				}
+				// tables show a summary of the parts
+				rows := func() interface{} {
+					rows := []searchResultRow{}
+					for _, part := range page.Parts {
+						row := searchResultRow{Name: part.Name, Version: part.Version, ID: part.ID.String(), License: part.License}
+						if !withArchives {
+							rows = append(rows, row)
+							continue
+						}
+						for _, archive := range page.Archives[part.ID] {
+							row.Archive, row.Sha256, row.InsertDate = archive.Name, archive.Sha256, archive.InsertDate
+							rows = append(rows, row)
+						}
+					}
+					return rows
 				}
-				fmt.Printf("Result: %s\n", string(prettyJson))
+				return printSearchResult(cmd, configFile, result, rows, "parts", options.Offset, page.NextOffset, page.Total)
 			}
 			return nil
 		},
 	}
+	addSearchFlags(findPartCmd, &options)
+	findPartCmd.Flags().BoolVar(&withArchives, "with-archives", false, "Also show the archives found for every part with their hashes and insert dates")
 	return findPartCmd
 }
 
+// FindArchive() handles finding the uploaded archives based on a search
+// query, or a single archive based on one of its hashes
+func FindArchive(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	var options graphql.SearchOptions
+	var sha256, sha1, md5 string
+	findArchiveCmd := &cobra.Command{
+		Use:   "archive [search query]",
+		Short: "Find the uploaded archives using their name(i.e. search query) or one of their hashes",
+		Long: `Find the uploaded archive files using their name(i.e. search query). The archives
+found are sorted by name and insert date and shown as a table of name, size,
+sha256, insert date and part unless an output format is selected, which also
+shows the sha1 and md5 of the archives. The filters apply to the part of the
+archives. Results are paged like the results of find part.
+
+With --sha256, --sha1 or --md5 instead of a search query the archive with the
+given hash is shown together with the part it belongs to.`,
+		// function to be run as a setup for command execution
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if sha256 != "" || sha1 != "" || md5 != "" {
+				if len(args) > 0 {
+					return usageError(errors.New("Please provide either a search query or a hash of the archive."))
+				}
+				return nil
+			}
+			if len(args) == 0 {
+				return usageError(errors.New("No archive name, search query or hash provided."))
+			}
+			return checkSearchFlags(cmd, &options)
+		},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var archive *graphql.Archive
+			var err error
+			switch {
+			case sha256 != "":
+				slog.Debug("retrieving archive by sha256", slog.String("SHA256", sha256))
+				archive, err = graphql.GetArchiveBySha256(cmd.Context(), client, sha256)
+			case sha1 != "":
+				slog.Debug("retrieving archive by sha1", slog.String("SHA1", sha1))
+				archive, err = graphql.GetArchiveBySha1(cmd.Context(), client, sha1)
+			case md5 != "":
+				slog.Debug("retrieving archive by md5", slog.String("MD5", md5))
+				archive, err = graphql.GetArchiveByMd5(cmd.Context(), client, md5)
+			default:
+				return findArchives(cmd, configFile, client, args[0], options)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "error retrieving archive")
+			}
+			result := archiveWithPart{partArchive: newPartArchive(*archive)}
+			if archive.PartID != uuid.Nil {
+				result.PartID = archive.PartID.String()
+			}
+			if archive.Part.ID != uuid.Nil {
+				result.Part = &archive.Part
+			}
+			return newPrinter(cmd, configFile).Print(result)
+		},
+	}
+	addSearchFlags(findArchiveCmd, &options)
+	findArchiveCmd.Flags().StringVar(&sha256, "sha256", "", "Sha256 of the archive to show")
+	findArchiveCmd.Flags().StringVar(&sha1, "sha1", "", "Sha1 of the archive to show")
+	findArchiveCmd.Flags().StringVar(&md5, "md5", "", "Md5 of the archive to show")
+	findArchiveCmd.MarkFlagsMutuallyExclusive("sha256", "sha1", "md5")
+	return findArchiveCmd
+}
+
+// findArchives() prints a page of the archives found by the search query
+func findArchives(cmd *cobra.Command, configFile *config.ConfigData, client *graph.Client, searchQuery string, options graphql.SearchOptions) error {
+	// search the catalog for the archives using the search query
+	slog.Debug("executing archive search", slog.String("Query", searchQuery), slog.String("Method", options.Method), slog.Int("Limit", options.Limit), slog.Int("Offset", options.Offset))
+	page, err := graphql.SearchArchives(cmd.Context(), client, searchQuery, options)
+	if err != nil {
+		return errors.Wrapf(err, "error searching for archive")
+	}
+	// tables show a summary of the archives
+	rows := func() interface{} {
+		rows := make([]archiveResultRow, len(page.Archives))
+		for i, archive := range page.Archives {
+			rows[i] = archiveResultRow{Name: archive.Name, Size: archive.Size, Sha256: archive.Sha256, InsertDate: archive.InsertDate}
+			if archive.Part.ID != uuid.Nil {
+				rows[i].Part = strings.TrimSuffix(archive.Part.Name+"-"+archive.Part.Version, "-")
+				rows[i].PartID = archive.Part.ID.String()
+			}
+		}
+		return rows
+	}
+	return printSearchResult(cmd, configFile, page.Archives, rows, "archives", options.Offset, page.NextOffset, page.Total)
+}
+
+// addSearchFlags() adds the flags selecting the search method, the
+// page and the filters of a search to the command
+func addSearchFlags(cmd *cobra.Command, options *graphql.SearchOptions) {
+	cmd.Flags().StringVar(&options.Method, "method", graphql.SearchMethods[0], "Search method ("+strings.Join(graphql.SearchMethods, ", ")+")")
+	cmd.Flags().IntVar(&options.Limit, "limit", 50, "Maximum number of results to show, 0 for all of them")
+	cmd.Flags().IntVar(&options.Offset, "offset", 0, "Number of results to skip")
+	cmd.Flags().String("cursor", "", "Cursor of the next page printed by a previous search")
+	cmd.Flags().StringVar(&options.Type, "type", "", "Only show parts of the given type")
+	cmd.Flags().StringVar(&options.ContentType, "content-type", "", "Only show parts of the given content type")
+	cmd.Flags().StringVar(&options.License, "license", "", "Only show parts under the given license, e.g. MIT")
+	cmd.Flags().StringVar(&options.FamilyName, "family-name", "", "Only show parts of the given family")
+	cmd.MarkFlagsMutuallyExclusive("offset", "cursor")
+}
+
+// checkSearchFlags() checks the search flags of the command and
+// sets the offset of the search to the one of the cursor
+func checkSearchFlags(cmd *cobra.Command, options *graphql.SearchOptions) error {
+	if cursor, _ := cmd.Flags().GetString("cursor"); cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return usageError(err)
+		}
+		options.Offset = offset
+	}
+	if options.Limit < 0 || options.Offset < 0 {
+		return usageError(errors.New("limit and offset must not be negative"))
+	}
+	if !graphql.IsSearchMethod(options.Method) {
+		return usageError(errors.Errorf("invalid search method %q, must be one of %s", options.Method, strings.Join(graphql.SearchMethods, ", ")))
+	}
+	return nil
+}
+
+// printSearchResult() prints a page of search results, as a table unless an output
+// format is selected. Tables and csv show the rows given by the rows function,
+// the other formats the result. The cursor of the next page is written to stderr.
+func printSearchResult(cmd *cobra.Command, configFile *config.ConfigData, result interface{}, rows func() interface{}, noun string, offset int, nextOffset int, total int) error {
+	printer := newPrinter(cmd, configFile)
+	if !formatIsExplicit(cmd) {
+		printer.Format = output.Table
+	}
+	if printer.Template == "" && printer.JSONPath == "" && (printer.Format == output.Table || printer.Format == output.CSV) {
+		result = rows()
+	}
+	if err := printer.Print(result); err != nil {
+		return err
+	}
+	if nextOffset > 0 {
+		printer.Info("Showing %d-%d of %d %s, next page: --cursor %s", offset+1, nextOffset, total, noun, encodeCursor(nextOffset))
+	}
+	return nil
+}
+
+// encodeCursor() gives the opaque cursor of the search results page at the offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor() gives the offset of the search results page of the cursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(data), "offset:") {
+		if offset, err := strconv.Atoi(strings.TrimPrefix(string(data), "offset:")); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, errors.Errorf("invalid cursor %q", cursor)
+}
+
 // FindId() handles finding a part based on part id
-func FindId(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func FindId(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for find using id
 	findIdCmd := &cobra.Command{
 		Use:   "id [part id]",
@@ -89,7 +339,7 @@ func FindId(configFile *config.ConfigData, client *graph.Client, indent string)
 		// function to be run as a setup before command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return errors.New("No part id provided.")
+				return usageError(errors.New("No part id provided."))
 			}
 			return nil
 		},
@@ -99,16 +349,11 @@ func FindId(configFile *config.ConfigData, client *graph.Client, indent string)
 			if argPartID != "" {
 				// find the part using the id
 				slog.Debug("retrieving part by id", slog.String("ID", argPartID))
-				response, err := graphql.GetPartByID(context.Background(), client, argPartID)
+				response, err := graphql.GetPartByID(cmd.Context(), client, argPartID)
 				if err != nil {
 					return errors.Wrapf(err, "error getting part by id")
 				}
-				// marshal the response struct to a json
-				prettyJson, err := json.MarshalIndent(&response, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
-				}
-				fmt.Printf("%s\n", string(prettyJson))
+				return newPrinter(cmd, configFile).Print(response)
 			}
 			return nil
 		},
@@ -125,7 +370,7 @@ func FindSha(configFile *config.ConfigData, client *graph.Client) *cobra.Command
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return errors.New("No sha256 provided.")
+				return usageError(errors.New("No sha256 provided."))
 			}
 			return nil
 		},
@@ -135,11 +380,11 @@ func FindSha(configFile *config.ConfigData, client *graph.Client) *cobra.Command
 			if argSHA256 != "" {
 				// get the part id using sha256
 				slog.Debug("retrieving part id by sha256", slog.String("SHA256", argSHA256))
-				partID, err := graphql.GetPartIDBySha256(context.Background(), client, argSHA256)
+				partID, err := graphql.GetPartIDBySha256(cmd.Context(), client, argSHA256)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving part id")
 				}
-				fmt.Printf("Part ID: %s \n", partID.String())
+				return newPrinter(cmd, configFile).Print(partIDResult{PartID: partID.String()})
 			}
 			return nil
 		},
@@ -156,7 +401,7 @@ func FindFvc(configFile *config.ConfigData, client *graph.Client) *cobra.Command
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return errors.New("No file verification code provided.")
+				return usageError(errors.New("No file verification code provided."))
 			}
 			return nil
 		},
@@ -166,11 +411,11 @@ func FindFvc(configFile *config.ConfigData, client *graph.Client) *cobra.Command
 			if argFVC != "" {
 				// get the part id using the file verification code
 				slog.Debug("retrieving part id by file verification code", slog.String("File Verification Code", argFVC))
-				partID, err := graphql.GetPartIDByFVC(context.Background(), client, argFVC)
+				partID, err := graphql.GetPartIDByFVC(cmd.Context(), client, argFVC)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving part id")
 				}
-				fmt.Printf("Part ID: %s \n", partID.String())
+				return newPrinter(cmd, configFile).Print(partIDResult{PartID: partID.String()})
 			}
 			return nil
 		},
@@ -178,9 +423,52 @@ func FindFvc(configFile *config.ConfigData, client *graph.Client) *cobra.Command
 	return findFvcCmd
 }
 
+// FindFile() handles finding a part based on the hashes of a local file, directory or archive
+func FindFile(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	// cobra command for find using a local file
+	findFileCmd := &cobra.Command{
+		Use:   "file [file|dir|archive]",
+		Short: "Find a part using the sha256 or the file verification code computed from a local file, directory or archive",
+		// function to be run as setup for command execution
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usageError(errors.New("No file, directory or archive provided."))
+			}
+			return nil
+		},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argPath := args[0]
+			slog.Debug("computing hashes", slog.String("Path", argPath))
+			sums, err := computeSums(cmd, argPath)
+			if err != nil {
+				return err
+			}
+			// look the part up by the sha256 of the file first, directories only have a file verification code
+			if sums.Sha256 != "" {
+				slog.Debug("retrieving part by sha256", slog.String("SHA256", sums.Sha256))
+				part, err := graphql.GetPartBySHA256(cmd.Context(), client, sums.Sha256)
+				if err == nil {
+					return newPrinter(cmd, configFile).Print(part)
+				}
+				if !errors.Is(err, graphql.ErrPartNotFound) {
+					return errors.Wrapf(err, "error retrieving part")
+				}
+			}
+			slog.Debug("retrieving part by file verification code", slog.String("File Verification Code", sums.FileVerificationCode))
+			part, err := graphql.GetPartByFVC(cmd.Context(), client, sums.FileVerificationCode)
+			if err != nil {
+				return errors.Wrapf(err, "error retrieving part")
+			}
+			return newPrinter(cmd, configFile).Print(part)
+		},
+	}
+	return findFileCmd
+}
+
 // FindProfile() handles finding a specific type of part profile
 // using its part id.
-func FindProfile(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func FindProfile(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for finding profile
 	findProfileCmd := &cobra.Command{
 		Use:   "profile [profile type] [part id]",
@@ -188,7 +476,7 @@ func FindProfile(configFile *config.ConfigData, client *graph.Client, indent str
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 2 {
-				return errors.New("No profile type and part id provided.")
+				return usageError(errors.New("No profile type and part id provided."))
 			}
 			return nil
 		},
@@ -198,26 +486,20 @@ func FindProfile(configFile *config.ConfigData, client *graph.Client, indent str
 			argPartID := args[1]
 			if argProfileType != "" {
 				if argPartID == "" {
-					return errors.New("error getting profile, missing part id")
+					return usageError(errors.New("error getting profile, missing part id"))
 				}
 				// get the particular profile for a part based on profile type and part id
 				slog.Debug("retrieving profile", slog.String("ID", argPartID), slog.String("Key", argProfileType))
-				profile, err := graphql.GetProfile(context.Background(), client, argPartID, argProfileType)
+				profile, err := graphql.GetProfile(cmd.Context(), client, argPartID, argProfileType)
 				if err != nil {
 					return errors.Wrapf(err, "error retrieving profile")
 				}
+				printer := newPrinter(cmd, configFile)
 				// check if any profiles were found
 				if len(*profile) < 1 {
-					fmt.Println("No documents found")
-					os.Exit(0)
-				}
-				// marshal the profile data into a json
-				prettyJson, err := json.MarshalIndent(&profile, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
+					printer.Info("No documents found")
 				}
-				fmt.Printf("%s\n", string(prettyJson))
-				os.Exit(0)
+				return printer.Print(profile)
 			}
 			return nil
 		},
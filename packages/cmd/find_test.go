@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"wrs/catalog/ccli/packages/graphql"
+
+	"github.com/google/uuid"
+)
+
+// keys of the json output of the commands
+var snakeCaseKey = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// TestFindResultKeys checks that the results of find use snake_case json keys only
+func TestFindResultKeys(tester *testing.T) {
+	part := graphql.Part{ID: uuid.New(), Name: "busybox", Version: "1.35.0", Aliases: []string{"bb"}}
+	archive := graphql.Archive{Name: "busybox-1.35.0.tar.bz2", Sha256: "b070", PartID: part.ID, Part: part}
+	results := map[string]interface{}{
+		"find part --with-archives": []partWithArchives{{Part: part, Archives: []partArchive{{Name: archive.Name, Sha256: archive.Sha256}}}},
+		"find archive":              []graphql.Archive{archive},
+		"find archive --sha256":     archiveWithPart{partArchive: newPartArchive(archive), PartID: part.ID.String(), Part: &part},
+	}
+	for name, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			tester.Fatalf("%s: failed to encode result: %v", name, err)
+		}
+		var decoded interface{}
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			tester.Fatalf("%s: failed to decode result: %v", name, err)
+		}
+		for _, key := range jsonKeys(decoded) {
+			if !snakeCaseKey.MatchString(key) {
+				tester.Errorf("%s: expected snake_case keys but got %q", name, key)
+			}
+		}
+	}
+}
+
+// jsonKeys() gives the keys of all the objects in the decoded json value
+func jsonKeys(value interface{}) []string {
+	var keys []string
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			keys = append(keys, key)
+			keys = append(keys, jsonKeys(child)...)
+		}
+	case []interface{}:
+		for _, child := range value {
+			keys = append(keys, jsonKeys(child)...)
+		}
+	}
+	return keys
+}
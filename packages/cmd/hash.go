@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"log/slog"
+	"wrs/catalog/ccli/packages/checksum"
+	"wrs/catalog/ccli/packages/config"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Hash() computes the hashes and the file verification code of
+// files, directories and archives without contacting the catalog
+func Hash(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for hashing
+	hashCmd := &cobra.Command{
+		Use:   "hash [file|dir|archive]...",
+		Short: "Compute the sha256, sha1, md5 and file verification code of files, directories and archives",
+		Long: `Compute the sha256, sha1 and md5 of files and archives and the file verification
+code used by the catalog. The file verification code of a directory or a tar, tar.gz,
+tar.bz2 or zip archive is computed from the regular files in it, the one of any other
+file from the file itself. Directories have no hashes of their own. The results can be
+used with find sha256 and find fvc, or in a single step with find file.`,
+		// the command does not contact the catalog server
+		Annotations: map[string]string{AnnotationOffline: "true"},
+		// function to be run as setup for command execution
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usageError(errors.New("No file, directory or archive provided."))
+			}
+			return nil
+		},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var results []*checksum.Sums
+			for _, path := range args {
+				slog.Debug("computing hashes", slog.String("Path", path))
+				sums, err := computeSums(cmd, path)
+				if err != nil {
+					return err
+				}
+				results = append(results, sums)
+			}
+			// a single path gives a single result
+			if len(results) == 1 {
+				return newPrinter(cmd, configFile).Print(results[0])
+			}
+			return newPrinter(cmd, configFile).Print(results)
+		},
+	}
+	addSelectionFlags(hashCmd)
+	return hashCmd
+}
+
+// computeSums() computes the sums of the path, reporting unreadable files and archives as invalid input
+func computeSums(cmd *cobra.Command, path string) (*checksum.Sums, error) {
+	sums, err := checksum.Compute(cmd.Context(), path)
+	if err != nil {
+		if cmd.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, inputError(errors.Wrapf(err, "error computing hashes of %s", path))
+	}
+	return sums, nil
+}
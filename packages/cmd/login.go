@@ -0,0 +1,168 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	nethttp "net/http"
+	"os"
+	"strings"
+	"wrs/catalog/ccli/packages/config"
+	"wrs/catalog/ccli/packages/graphql"
+	"wrs/catalog/ccli/packages/http"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Login() verifies a bearer token against the catalog and stores it
+// in the credentials file for the configured server. The token is either
+// given by the user or obtained from the configured openid connect provider.
+func Login(configFile *config.ConfigData) *cobra.Command {
+	var argToken string
+	var argOIDC bool
+	var argClientCredentials bool
+	// cobra command for login
+	loginCmd := &cobra.Command{
+		Use:   "login [--token token | --oidc [--client-credentials]]",
+		Short: "Store a token for authenticating with the Software Parts Catalog",
+		Long: `Store a token for authenticating with the Software Parts Catalog. The token is read from standard input if --token is not given.
+With --oidc the token is obtained from the openid connect provider configured in auth.oidc using the device authorization grant,
+or the client credentials grant if --client-credentials is given. Openid connect tokens are refreshed automatically.`,
+		// the stored credentials may not be valid yet, login contacts
+		// the server itself to verify the new credentials
+		Annotations: map[string]string{AnnotationOffline: "true"},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := configFile.ValidateServer(); err != nil {
+				return configError(err)
+			}
+			transport, err := http.NewTransport(configFile.TLS)
+			if err != nil {
+				return errors.Wrapf(err, "error configuring http client")
+			}
+			serverCredentials := config.ServerCredentials{Token: argToken}
+			if argOIDC {
+				// obtain the token from the openid connect provider
+				token, err := oidcLogin(cmd.Context(), configFile.Auth.OIDC, &nethttp.Client{Transport: transport}, argClientCredentials)
+				if err != nil {
+					return err
+				}
+				serverCredentials = config.ServerCredentials{Token: token.AccessToken, RefreshToken: token.RefreshToken, Expiry: token.Expiry}
+			} else if serverCredentials.Token == "" {
+				// read the token from standard input if it is not given as a flag
+				if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+					fmt.Fprint(os.Stderr, "Token: ")
+				}
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return errors.Wrapf(err, "error reading token")
+				}
+				serverCredentials.Token = strings.TrimSpace(line)
+			}
+			if serverCredentials.Token == "" {
+				return usageError(errors.New("No token provided."))
+			}
+			if configFile.Auth.IsSet() {
+				slog.Warn("credentials are set by the configuration and take precedence over the stored token")
+			}
+			// verify the token by running a query with it
+			httpClient := &nethttp.Client{Transport: http.WithAuth(transport, config.AuthConfig{Token: serverCredentials.Token})}
+			slog.Debug("verifying token", slog.String("Address", configFile.ServerAddr))
+			if _, err = graphql.Query(cmd.Context(), graphql.GetNewClient(configFile.ServerAddr, httpClient), "query{__typename}"); err != nil {
+				return errors.Wrapf(err, "error verifying token")
+			}
+			// store the token for the server
+			if err = config.StoreServerCredentials(configFile.ServerAddr, serverCredentials); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Login Succeeded: %s\n", configFile.ServerAddr)
+			return nil
+		},
+	}
+	loginCmd.Flags().StringVar(&argToken, "token", "", "Bearer token to authenticate with")
+	loginCmd.Flags().BoolVar(&argOIDC, "oidc", false, "Obtain the token from the configured openid connect provider")
+	loginCmd.Flags().BoolVar(&argClientCredentials, "client-credentials", false, "Use the client credentials grant instead of the device authorization grant with --oidc")
+	loginCmd.MarkFlagsMutuallyExclusive("token", "oidc")
+	return loginCmd
+}
+
+// oidcLogin() obtains a token from the openid connect provider using the device
+// authorization grant, or the client credentials grant for non interactive use
+func oidcLogin(ctx context.Context, oidcConfig config.OIDCConfig, httpClient *nethttp.Client, clientCredentials bool) (*http.Token, error) {
+	if oidcConfig.Issuer == "" || oidcConfig.ClientID == "" {
+		return nil, configError(errors.New("error logging in, auth.oidc.issuer and auth.oidc.client_id must be configured"))
+	}
+	slog.Debug("discovering openid connect provider", slog.String("Issuer", oidcConfig.Issuer))
+	provider, err := http.DiscoverOIDC(ctx, httpClient, oidcConfig.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if clientCredentials {
+		token, err := provider.ClientCredentialsToken(ctx, httpClient, oidcConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error obtaining token")
+		}
+		return token, nil
+	}
+	deviceCode, err := provider.DeviceAuthorization(ctx, httpClient, oidcConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error starting device authorization")
+	}
+	// instructions go to stderr to keep stdout for the result
+	if deviceCode.VerificationURIComplete != "" {
+		fmt.Fprintf(os.Stderr, "To log in, open %s and confirm the code %s\n", deviceCode.VerificationURIComplete, deviceCode.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "To log in, open %s and enter the code %s\n", deviceCode.VerificationURI, deviceCode.UserCode)
+	}
+	token, err := provider.PollDeviceToken(ctx, httpClient, oidcConfig, deviceCode)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error obtaining token")
+	}
+	return token, nil
+}
+
+// Logout() removes the stored token of the configured server
+// from the credentials file
+func Logout(configFile *config.ConfigData) *cobra.Command {
+	// cobra command for logout
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the stored token for the Software Parts Catalog",
+		// the command does not contact the catalog server
+		Annotations: map[string]string{AnnotationOffline: "true"},
+		// function to be run during command execution
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := configFile.ValidateServer(); err != nil {
+				return configError(err)
+			}
+			credentials, err := config.ReadCredentials()
+			if err != nil {
+				return err
+			}
+			if _, ok := credentials.Servers[configFile.ServerAddr]; !ok {
+				fmt.Fprintf(os.Stderr, "Not logged in to %s\n", configFile.ServerAddr)
+				return nil
+			}
+			delete(credentials.Servers, configFile.ServerAddr)
+			if err = credentials.Write(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Removing login credentials for %s\n", configFile.ServerAddr)
+			return nil
+		},
+	}
+	return logoutCmd
+}
@@ -12,8 +12,10 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
+	nethttp "net/http"
 	"wrs/catalog/ccli/packages/config"
+	"wrs/catalog/ccli/packages/graphql"
 	"wrs/catalog/ccli/packages/http"
 
 	"log/slog"
@@ -22,6 +24,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// struct for the result of pinging the catalog
+type pingResult struct {
+	Server string `json:"server"`
+	Status string `json:"status"`
+}
+
 // Ping() makes a call to the catalog server and checks if the catalog server
 // is responding and ready for further api calls.
 func Ping(configFile *config.ConfigData) *cobra.Command {
@@ -34,22 +42,36 @@ func Ping(configFile *config.ConfigData) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// check if the server address is nil
 			if configFile.ServerAddr == "" {
-				return errors.New("invalid configuration file, no server address located")
+				return configError(errors.New("invalid configuration file, no server address located"))
 			}
 			slog.Debug("Pinging server", slog.String("Address", configFile.ServerAddr))
 			// ping the server
-			resp, err := http.DefaultClient.Get(configFile.ServerAddr)
-			if err != nil {
-				return errors.New("error contacting server")
+			if err := checkServer(cmd.Context(), configFile.ServerAddr); err != nil {
+				return err
 			}
-			resp.Body.Close()
-			// check if the response's status code is valid
-			if resp.StatusCode != 200 && resp.StatusCode != 422 {
-				return errors.New("error reaching server, status code:" + fmt.Sprint(resp.StatusCode))
-			} else {
-				fmt.Println("Ping Result: Success")
-			}
-			return nil
+			return newPrinter(cmd, configFile).Print(pingResult{Server: configFile.ServerAddr, Status: "success"})
 		},
 	}
 }
+
+// checkServer() contacts the server at the given address and checks if
+// the response suggests a successful connection to the catalog
+func checkServer(ctx context.Context, serverAddr string) error {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, serverAddr, nil)
+	if err != nil {
+		return configError(errors.Wrapf(err, "invalid server address"))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "error contacting server")
+		}
+		return &graphql.RequestError{Err: err}
+	}
+	resp.Body.Close()
+	// check if the response's status code is valid
+	if resp.StatusCode != 200 && resp.StatusCode != 422 {
+		return &graphql.RequestError{StatusCode: resp.StatusCode, Err: errors.New("error reaching server")}
+	}
+	return nil
+}
@@ -12,9 +12,7 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
 	"log/slog"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
@@ -25,15 +23,15 @@ import (
 )
 
 // Query() handles the execution of a given graphql query
-func Query(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Query(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for graphql query
-	return &cobra.Command{
+	queryCmd := &cobra.Command{
 		Use:   "query [graphql query]",
 		Short: "Query the Software Parts Catalog",
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No query provided.")
+				return usageError(errors.New("No query provided."))
 			}
 			return nil
 		},
@@ -41,27 +39,21 @@ func Query(configFile *config.ConfigData, client *graph.Client, indent string) *
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argQuery := args[0]
 			if argQuery == "" {
-				return errors.New("error executing user query, query subcommand usage: ccli query <GraphQL Query>")
+				return usageError(errors.New("error executing user query, query subcommand usage: ccli query <GraphQL Query>"))
 			}
 			// check if the query is not nil and run the graphql query
 			if argQuery != "" {
 				slog.Debug("executing raw graphql query")
-				response, err := graphql.Query(context.Background(), client, argQuery)
+				response, err := graphql.Query(cmd.Context(), client, argQuery)
 				if err != nil {
 					return errors.Wrapf(err, "error querying graphql")
 				}
-
-				// json result will be output in prettified format
-				var data map[string]interface{}
-				json.Unmarshal(response, &data)
-				// marshal the response into a json
-				prettyJson, err := json.MarshalIndent(data, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
-				}
-				fmt.Println(string(prettyJson))
+				// the raw json result keeps the order of the queried fields
+				return newPrinter(cmd, configFile).Print(json.RawMessage(response))
 			}
 			return nil
 		},
 	}
+	addSelectionFlags(queryCmd)
+	return queryCmd
 }
@@ -12,46 +12,248 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"wrs/catalog/ccli/packages/config"
+	"wrs/catalog/ccli/packages/graphql"
+	"wrs/catalog/ccli/packages/http"
+	"wrs/catalog/ccli/packages/output"
 
 	"github.com/pkg/errors"
 
 	"log/slog"
 
+	graph "github.com/hasura/go-graphql-client"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// annotation marking commands which can be run without contacting the
+// catalog server, sub commands inherit the annotation of their parents
+const AnnotationOffline = "offline"
+
+// annotation marking commands which may take longer than the configured timeout,
+// such as uploads, they are only limited by a timeout given by --timeout or
+// the environment since the default configuration file sets one
+const AnnotationLongRunning = "long_running"
+
 // RootCmd() is the root command which results in an error and a usage
-// message advising the user to add sub commands
-func RootCmd(configFile *config.ConfigData, logWriter *config.LogWriter) *cobra.Command {
+// message advising the user to add sub commands. Before any sub command
+// is executed the configuration file is resolved and read into configFile
+// and the given client is connected to the configured server, which is
+// checked for reachability unless the command is marked as offline.
+func RootCmd(configFile *config.ConfigData, logWriter *config.LogWriter, client *graph.Client) *cobra.Command {
 	var verboseFlag bool
+	var configFlag string
+	var contextFlag string
+	// cancels the context of the executed command once it has finished
+	cancel := context.CancelFunc(func() {})
 	// cobra command for root ccli
 	rootCmd := &cobra.Command{
 		Use:   "ccli",
 		Short: "Ccli is used to interact with the Software Parts Catalog.",
+		// errors and the usage for usage errors are reported by Execute()
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		// function which is always to be reun before command execution
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// check if the verbose flag is on
+			// the commands generated by cobra for help and shell completion need no setup
+			if isCobraCommand(cmd) {
+				return nil
+			}
+			// resolve the configuration file and read it
+			configPath, err := config.ConfigPath(configFlag)
+			if err != nil {
+				return configError(err)
+			}
+			if err = configFile.Load(configPath, contextFlag); err != nil {
+				return configError(err)
+			}
+			format, err := output.ParseFormat(configFile.Format)
+			if err != nil {
+				return configError(err)
+			}
+			configFile.Format = string(format)
+			// create the log file or truncate it if already present
+			logFile, err := os.Create(configFile.LogFile)
+			if err != nil {
+				return configError(errors.Wrapf(err, "error opening log file"))
+			}
+			// create a new log writer for writing to the log file and stdout simultaneously
+			*logWriter = config.LogWriter{Stdout: os.Stderr, File: logFile}
+			slogOptions := new(slog.HandlerOptions)
+			slogOptions.Level = slog.LevelDebug
+			// if the log level is 2, add he source information to the options
+			if configFile.LogLevel == 2 {
+				slogOptions.AddSource = true
+			}
+			// check if the verbose flag is on and set the default slog logger
+			// to the log file, and stderr if verbose, with the given log level
 			if verboseFlag {
-				// create ne logger options and level
-				slogOptions := new(slog.HandlerOptions)
-				slogOptions.Level = slog.LevelDebug
-				// if the log level is 2, add he source information to the options
-				if configFile.LogLevel == 2 {
-					slogOptions.AddSource = true
-				}
-				// set a new default for logging
 				slog.SetDefault(slog.New(slog.NewJSONHandler(logWriter, slogOptions)))
+			} else {
+				slog.SetDefault(slog.New(slog.NewJSONHandler(logWriter.File, slogOptions)))
+			}
+			slog.Debug("slog.SetDefault JSONHandler", slog.Group("HandlerOptions", slog.Bool("AddSource", slogOptions.AddSource), slog.Any("Level", slogOptions.Level)))
+			slog.Debug("using configuration file", slog.String("Path", configFile.Path), slog.String("Context", configFile.Context))
+			if configFile.MissingContext != "" {
+				slog.Warn("current context not found in configuration file, using the top level settings", slog.String("Context", configFile.MissingContext))
+				if !verboseFlag {
+					fmt.Fprintf(os.Stderr, "Warning: current context %q not found in configuration file, using the top level settings\n", configFile.MissingContext)
+				}
+			}
+			// cancel the command on an interrupt or termination signal and once the timeout is exceeded
+			var ctx context.Context
+			ctx, cancel = commandContext(cmd.Context(), commandTimeout(cmd, configFile))
+			cmd.SetContext(ctx)
+			// point the graphql client to the configured server
+			*client = *graphql.GetNewClient(configFile.ServerAddr, http.DefaultClient)
+			// offline capable commands are run without contacting the server
+			if IsOffline(cmd) {
+				slog.Debug("skipping server check for offline command", slog.String("Command", cmd.CommandPath()))
+				return nil
+			}
+			if err = configFile.ValidateServer(); err != nil {
+				return configError(err)
+			}
+			// use the credentials stored by login if none are configured
+			if err = configFile.ApplyCredentials(); err != nil {
+				return configError(err)
+			}
+			// apply the tls settings and credentials to the http client shared by all catalog requests
+			if err = http.Configure(configFile); err != nil {
+				return configError(errors.Wrapf(err, "error configuring http client"))
 			}
+			// contact the given server
+			if err = checkServer(cmd.Context(), configFile.ServerAddr); err != nil {
+				return errors.Wrapf(err, "server connection error, check config file and network configuration")
+			}
+			slog.Debug("successfully connected to server")
 			return nil
 		},
+		// function which is always to be run after command execution
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			cancel()
+		},
 		// function to be run during command execution
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("Please provide a sub-command to be executed. Refer to the examples by running ccli examples or use help for more information.")
+			return usageError(errors.New("Please provide a sub-command to be executed. Refer to the examples by running ccli examples or use help for more information."))
 
 		},
 	}
+	// mistyped and missing flags are usage errors
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
 	// add a flag to the root command for having a verbose value
 	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "To Execute commands in verbose mode")
+	// add a flag to the root command for an explicit configuration file
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Path to the configuration file (default: $"+config.ConfigEnv+", $XDG_CONFIG_HOME/ccli/config.yml, ~/.ccli.yml, ./"+config.DefaultConfigFile+")")
+	// add a flag to the root command for selecting a named context of the configuration file
+	rootCmd.PersistentFlags().StringVar(&contextFlag, "context", "", "Name of the configuration context to use instead of the current context")
+	// add flags to the root command overriding the configuration file and environment
+	rootCmd.PersistentFlags().String("server", "", "Catalog server address, overrides server_addr and $"+config.EnvName("server_addr"))
+	rootCmd.PersistentFlags().String("log-file", "", "Path to the log file, overrides log_file and $"+config.EnvName("log_file"))
+	rootCmd.PersistentFlags().Int64("log-level", 0, "Log level (1 or 2), overrides log_level and $"+config.EnvName("log_level"))
+	rootCmd.PersistentFlags().Int64("indent", 0, "Json output indentation, overrides json_indent and $"+config.EnvName("json_indent"))
+	rootCmd.PersistentFlags().String("format", "", "Output format of the results (json, yaml, table or csv), overrides format and $"+config.EnvName("format"))
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Time limit for the whole command such as 30s or 10m, 0 disables it, overrides timeout and $"+config.EnvName("timeout"))
+	// bind the override flags to their configuration keys
+	viper.BindPFlag("server_addr", rootCmd.PersistentFlags().Lookup("server"))
+	viper.BindPFlag("log_file", rootCmd.PersistentFlags().Lookup("log-file"))
+	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("json_indent", rootCmd.PersistentFlags().Lookup("indent"))
+	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
 	return rootCmd
 }
+
+// commandContext() derives the context of the executed command, which is
+// cancelled by SIGINT or SIGTERM and after the given timeout unless it is 0.
+// The signals are only caught once, so a second Ctrl-C terminates ccli at once.
+func commandContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	cancelTimeout := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, func() {
+		cancelTimeout()
+		stop()
+	}
+}
+
+// commandTimeout() gives the timeout of the command, which is disabled for long
+// running commands unless it is given by the timeout flag or the environment
+func commandTimeout(cmd *cobra.Command, configFile *config.ConfigData) time.Duration {
+	if cmd.Annotations[AnnotationLongRunning] != "true" {
+		return configFile.Timeout
+	}
+	if cmd.Root().PersistentFlags().Changed("timeout") || os.Getenv(config.EnvName("timeout")) != "" {
+		return configFile.Timeout
+	}
+	slog.Debug("no timeout for long running command", slog.String("Command", cmd.CommandPath()))
+	return 0
+}
+
+// newPrinter() gives the printer for the results of a command in the
+// configured output format, or the template given by the command's flags
+func newPrinter(cmd *cobra.Command, configFile *config.ConfigData) *output.Printer {
+	printer := output.New(output.Format(configFile.Format), configFile.Indent())
+	if flag := cmd.Flags().Lookup("template"); flag != nil {
+		printer.Template = flag.Value.String()
+	}
+	if flag := cmd.Flags().Lookup("jsonpath"); flag != nil {
+		printer.JSONPath = flag.Value.String()
+	}
+	return printer
+}
+
+// formatIsExplicit() checks if the output format is selected by the format flag
+// or the environment. The format of the configuration file is not an explicit
+// choice since the default configuration file sets it.
+func formatIsExplicit(cmd *cobra.Command) bool {
+	return cmd.Root().PersistentFlags().Changed("format") || os.Getenv(config.EnvName("format")) != ""
+}
+
+// addSelectionFlags() adds the flags selecting the output of a command with
+// a go template or a jsonpath template, which are inherited by sub commands
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("template", "", "Go template applied to the result instead of the output format, e.g. '{{.Name}}-{{.Version}}'")
+	cmd.PersistentFlags().String("jsonpath", "", "JSONPath template applied to the result instead of the output format, e.g. '{.file_verification_code}'")
+	cmd.MarkFlagsMutuallyExclusive("template", "jsonpath")
+}
+
+// IsOffline() checks if the command or any of its parents
+// is annotated as not needing the catalog server
+func IsOffline(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[AnnotationOffline] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// isCobraCommand() checks if the command is one of the help and shell
+// completion commands added by cobra itself
+func isCobraCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return c.Parent() == cmd.Root()
+		}
+	}
+	return false
+}
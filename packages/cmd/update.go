@@ -12,15 +12,9 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"io"
 	"log/slog"
-	"os"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
-	"wrs/catalog/ccli/packages/yaml"
 
 	graph "github.com/hasura/go-graphql-client"
 	"github.com/pkg/errors"
@@ -29,7 +23,7 @@ import (
 
 // Update() is a sub command responsible for updating part information
 // based on a given yml file.
-func Update(configFile *config.ConfigData, client *graph.Client, indent string) *cobra.Command {
+func Update(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
 	// cobra command for update
 	updateCmd := &cobra.Command{
 		Use:   "update [path]",
@@ -38,7 +32,7 @@ func Update(configFile *config.ConfigData, client *graph.Client, indent string)
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// check if exactly 1 argument is present
 			if len(args) < 1 {
-				return errors.New("No path provided.")
+				return usageError(errors.New("No path provided."))
 			}
 			return nil
 		},
@@ -46,41 +40,23 @@ func Update(configFile *config.ConfigData, client *graph.Client, indent string)
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argImportPath := args[0]
 			if argImportPath == "" {
-				return errors.New("error updating part, update subcommand usage: ./ccli update <Path>")
+				return usageError(errors.New("error updating part, update subcommand usage: ./ccli update <Path>"))
 			}
-			// check if the file is of yaml/yml format
 			if argImportPath != "" {
-				if argImportPath[len(argImportPath)-5:] != ".yaml" && argImportPath[len(argImportPath)-4:] != ".yml" {
-					return errors.New("error importing part, import path not a yaml file")
-				}
-				// open the file
-				f, err := os.Open(argImportPath)
-				if err != nil {
-					return errors.Wrapf(err, "error opening file")
-				}
-				defer f.Close()
-				// read all the data from the file
-				data, err := io.ReadAll(f)
+				// read the part data of the yaml/yml file
+				partData, err := readPartFile(argImportPath)
 				if err != nil {
-					return errors.Wrapf(err, "error reading file")
-				}
-				// unmarshal the data of the file into a struct
-				var partData yaml.Part
-				if err = yaml.Unmarshal(data, &partData); err != nil {
-					return errors.Wrapf(err, "error decoding file contents")
+					return err
 				}
 				slog.Debug("updating part")
 				// update the part with the given part data
-				returnPart, err := graphql.UpdatePart(context.Background(), client, &partData)
+				returnPart, err := graphql.UpdatePart(cmd.Context(), client, partData)
 				if err != nil {
 					return errors.Wrapf(err, "error updating part")
 				}
-				// marshal the struct into a json
-				prettyJson, err := json.MarshalIndent(&returnPart, "", indent)
-				if err != nil {
-					return errors.Wrapf(err, "error prettifying json")
-				}
-				fmt.Printf("Part successfully updated\n%s\n", string(prettyJson))
+				printer := newPrinter(cmd, configFile)
+				printer.Info("Part successfully updated")
+				return printer.Print(returnPart)
 			}
 			return nil
 		},
@@ -12,27 +12,201 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"wrs/catalog/ccli/packages/checksum"
 	"wrs/catalog/ccli/packages/config"
 	"wrs/catalog/ccli/packages/graphql"
 	"wrs/catalog/ccli/packages/http"
+	"wrs/catalog/ccli/packages/output"
+	"wrs/catalog/ccli/packages/yaml"
 
+	"github.com/google/uuid"
+	graph "github.com/hasura/go-graphql-client"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// statuses of the files of a recursive upload
+const (
+	uploadStatusUploaded = "uploaded"
+	uploadStatusSkipped  = "skipped"
+	uploadStatusFailed   = "failed"
+)
+
+// struct for the result of uploading a file of a recursive upload
+type uploadFileResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	PartID string `json:"part_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// struct for the archive created by an upload, or the one the catalog already has
+type uploadArchiveResult struct {
+	Name       string `json:"name"`
+	Sha256     string `json:"sha256"`
+	Sha1       string `json:"sha1"`
+	InsertDate string `json:"insert_date"`
+	// left out if the catalog has not assigned a part to the archive yet
+	PartID string `json:"part_id,omitempty"`
+}
+
+// newUploadArchiveResult() gives the result of uploading the archive
+func newUploadArchiveResult(archive *graphql.Archive) uploadArchiveResult {
+	result := uploadArchiveResult{Name: archive.Name, Sha256: archive.Sha256, Sha1: archive.Sha1, InsertDate: archive.InsertDate}
+	if archive.PartID != uuid.Nil {
+		result.PartID = archive.PartID.String()
+	}
+	return result
+}
+
+// struct for the part of an uploaded archive after its part data and profiles were applied
+type uploadMetadataResult struct {
+	Part     *graphql.Part      `json:"part"`
+	Profiles []addProfileResult `json:"profiles"`
+}
+
+// uploadMetadata is the part data and the profiles applied to the part of an uploaded archive
+type uploadMetadata struct {
+	partPath string
+	part     *yaml.Part
+	profiles []uploadProfile
+}
+
+// uploadProfile is a profile of a yml file applied to the part of an uploaded archive
+type uploadProfile struct {
+	path     string
+	profile  *yaml.Profile
+	document json.RawMessage
+}
+
+// intervals of polling the catalog for the part of an uploaded archive
+const (
+	waitInterval    = time.Second
+	waitMaxInterval = 10 * time.Second
+)
+
+// uploadOptions are the flags of the upload command
+type uploadOptions struct {
+	force     bool
+	recursive bool
+	include   []string
+	exclude   []string
+	parallel  int
+	quiet     bool
+	// size of the chunks of resumable uploads in MiB
+	chunkSize int64
+	// wait until the catalog processed the uploaded archives
+	wait        bool
+	waitTimeout time.Duration
+	// yml files of the part data and the profiles of the uploaded archive
+	partFile     string
+	profileFiles []string
+}
+
+// readMetadata() reads the part data and the profiles given to the upload,
+// it gives nil if there are none
+func (options *uploadOptions) readMetadata() (*uploadMetadata, error) {
+	if options.partFile == "" && len(options.profileFiles) == 0 {
+		return nil, nil
+	}
+	metadata := &uploadMetadata{partPath: options.partFile}
+	if options.partFile != "" {
+		part, err := readPartFile(options.partFile)
+		if err != nil {
+			return nil, err
+		}
+		metadata.part = part
+	}
+	for _, path := range options.profileFiles {
+		profile, document, err := readProfileFile(path)
+		if err != nil {
+			return nil, err
+		}
+		metadata.profiles = append(metadata.profiles, uploadProfile{path: path, profile: profile, document: document})
+	}
+	return metadata, nil
+}
+
+// uploadOptions() gives the options of uploading a file with the retry policy of the configuration
+func (options *uploadOptions) uploadOptions(configFile *config.ConfigData) graphql.UploadOptions {
+	return graphql.UploadOptions{
+		ChunkSize:      options.chunkSize << 20,
+		MaxAttempts:    configFile.Retry.MaxAttempts,
+		InitialBackoff: configFile.Retry.InitialBackoff,
+		MaxBackoff:     configFile.Retry.MaxBackoff,
+	}
+}
+
 // Upload() uses the graphql upload library to upload
 // an archive present at the given path.
-func Upload(configFile *config.ConfigData) *cobra.Command {
+func Upload(configFile *config.ConfigData, client *graph.Client) *cobra.Command {
+	var options uploadOptions
 	// cobra command for upload
 	uploadCmd := &cobra.Command{
 		Use:   "upload [path]",
 		Short: "Upload an archive to the Software Parts Catalog",
+		// uploads and waiting for the catalog may take longer than the configured timeout
+		Annotations: map[string]string{AnnotationLongRunning: "true"},
+		Long: `Upload an archive to the Software Parts Catalog. The sha256 of the archive is
+computed first and the upload is skipped if the catalog already has the archive,
+showing the part it belongs to. Use --force to upload it anyway.
+
+With --recursive all files in the directory and its sub directories are uploaded
+by --parallel workers. --include and --exclude select the files by glob patterns
+matched against their path relative to the directory and their name. The result
+of every file (uploaded, skipped or failed) is shown followed by a summary, the
+command fails if any file failed to upload.
+
+A progress line with the bytes sent, the rate and the estimated time left is shown
+on stderr while a single archive is uploaded to a terminal, --quiet hides it along
+with all other messages. If the catalog supports resumable uploads the archive is
+sent in chunks of --chunk-size MiB and an upload interrupted by a dropped connection
+is resumed from the last chunk the catalog received.
+
+The catalog processes uploaded archives asynchronously. With --wait the command
+polls the catalog until the part of the archive is available, for at most
+--wait-timeout, and shows the part like find id instead of the upload response,
+e.g. to pass its id to add profile.
+
+Uploads are not limited by the timeout of the configuration file, only by a
+timeout given with --timeout or the environment.
+
+--part and --profile apply the part data and the profiles of yml files to the part
+of the archive once the catalog processed it, as update and add profile do. The
+part identifiers in the files are ignored, the part of the archive is updated.`,
 		// function to be run as setup for command execution
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
-				return errors.New("No path provided.")
+				return usageError(errors.New("No path provided."))
+			}
+			if options.parallel < 1 {
+				return usageError(errors.New("parallel must be at least 1"))
+			}
+			if options.chunkSize < 1 {
+				return usageError(errors.New("chunk size must be at least 1 MiB"))
+			}
+			for _, pattern := range append(options.include, options.exclude...) {
+				if _, err := filepath.Match(pattern, ""); err != nil {
+					return usageError(errors.Wrapf(err, "invalid pattern %q", pattern))
+				}
+			}
+			if options.waitTimeout <= 0 {
+				return usageError(errors.New("wait timeout must be positive"))
+			}
+			if !options.recursive && (len(options.include) > 0 || len(options.exclude) > 0) {
+				return usageError(errors.New("include and exclude patterns are only used with --recursive"))
+			}
+			if options.recursive && (options.partFile != "" || len(options.profileFiles) > 0) {
+				return usageError(errors.New("part data and profiles can not be applied with --recursive"))
 			}
 			return nil
 		},
@@ -40,28 +214,331 @@ func Upload(configFile *config.ConfigData) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			argPath := args[0]
 			if argPath == "" {
-				return errors.New("error executing upload, upload subcommand usage: ccli upload <Path>")
+				return usageError(errors.New("error executing upload, upload subcommand usage: ccli upload <Path>"))
+			}
+			info, err := os.Stat(argPath)
+			if err != nil {
+				return inputError(errors.Wrapf(err, "error uploading archive"))
+			}
+			if options.recursive != info.IsDir() {
+				if options.recursive {
+					return usageError(errors.Errorf("%s is not a directory", argPath))
+				}
+				return usageError(errors.Errorf("%s is a directory, use --recursive to upload the files in it", argPath))
+			}
+			if options.recursive {
+				return uploadRecursive(cmd, configFile, client, argPath, &options)
 			}
 			// check if the file path is present and upload it to the catalog
 			if argPath != "" {
-				slog.Debug("uploading file to server")
-				response, err := graphql.UploadFile(http.DefaultClient, configFile.ServerAddr, argPath, "")
+				printer := newPrinter(cmd, configFile)
+				if options.quiet {
+					printer.Err = io.Discard
+				}
+				// the files are read before the upload so that invalid files do not leave an archive behind
+				metadata, err := options.readMetadata()
 				if err != nil {
-					return errors.Wrapf(err, "error uploading archive")
+					return err
+				}
+				var sha256 string
+				if !options.force || options.wait || metadata != nil {
+					if sha256, err = fileSha256(argPath); err != nil {
+						return err
+					}
 				}
-				// check if the response is present
-				if response != nil {
-					if len(response.Errors) > 0 {
-						return errors.New(fmt.Sprintf("error uploading archive: %v", response.Errors))
+				if !options.force {
+					archive, err := existingArchive(cmd, client, sha256)
+					if err != nil {
+						return err
 					}
-					if response.Data != nil {
-						fmt.Printf("Successfully uploaded package: %s\n", argPath)
+					if archive != nil {
+						printer.Info("Archive %s already exists in the catalog as part %s, skipping upload (use --force to upload it anyway)", argPath, archive.PartID)
+						if options.wait || metadata != nil {
+							return waitForPart(cmd, client, printer, sha256, &options, metadata)
+						}
+						return printer.Print(newUploadArchiveResult(archive))
 					}
 				}
+				slog.Debug("uploading file to server")
+				uploadOptions := options.uploadOptions(configFile)
+				var progress *output.Progress
+				if !options.quiet && output.IsTerminal(os.Stderr) {
+					progress = output.NewProgress(os.Stderr, filepath.Base(argPath), info.Size())
+					uploadOptions.Progress = progress.Update
+				}
+				archive, err := graphql.UploadFile(cmd.Context(), http.DefaultClient, configFile.ServerAddr, argPath, "", uploadOptions)
+				if progress != nil {
+					progress.Done()
+				}
+				if err != nil {
+					return errors.Wrapf(err, "error uploading archive")
+				}
+				printer.Info("Successfully uploaded package: %s", argPath)
+				if options.wait || metadata != nil {
+					return waitForPart(cmd, client, printer, sha256, &options, metadata)
+				}
+				return printer.Print(newUploadArchiveResult(archive))
 			}
 			return nil
 		},
 	}
+	uploadCmd.Flags().BoolVar(&options.force, "force", false, "Upload the archive even if the catalog already has it")
+	uploadCmd.Flags().BoolVarP(&options.recursive, "recursive", "r", false, "Upload all files in the directory and its sub directories")
+	uploadCmd.Flags().StringSliceVar(&options.include, "include", nil, "Only upload files matching the glob pattern, e.g. '*.tar.gz' (repeatable, with --recursive)")
+	uploadCmd.Flags().StringSliceVar(&options.exclude, "exclude", nil, "Do not upload files matching the glob pattern, e.g. '*.sig' (repeatable, with --recursive)")
+	uploadCmd.Flags().IntVar(&options.parallel, "parallel", 4, "Number of files uploaded at the same time with --recursive")
+	uploadCmd.Flags().BoolVarP(&options.quiet, "quiet", "q", false, "Do not show the upload progress and other messages on stderr")
+	uploadCmd.Flags().Int64Var(&options.chunkSize, "chunk-size", graphql.DefaultChunkSize>>20, "Size of the chunks of resumable uploads in MiB")
+	uploadCmd.Flags().BoolVar(&options.wait, "wait", false, "Wait until the catalog processed the archive and show its part")
+	uploadCmd.Flags().DurationVar(&options.waitTimeout, "wait-timeout", 3*time.Minute, "Maximum time to wait for the catalog to process the archive with --wait")
+	uploadCmd.Flags().StringVar(&options.partFile, "part", "", "Update the part of the archive with the part data of the yml file, implies --wait")
+	uploadCmd.Flags().StringArrayVar(&options.profileFiles, "profile", nil, "Add the profile of the yml file to the part of the archive, implies --wait (repeatable)")
+	addSelectionFlags(uploadCmd)
 	return uploadCmd
+}
+
+// fileSha256() gives the sha256 of the file to upload
+func fileSha256(path string) (string, error) {
+	sha256, err := checksum.FileSha256(path)
+	if err != nil {
+		return "", inputError(errors.Wrapf(err, "error computing sha256 of %s", path))
+	}
+	return sha256, nil
+}
+
+// existingArchive() gives the archive of the catalog with the
+// sha256, or nil if the catalog does not have the archive
+func existingArchive(cmd *cobra.Command, client *graph.Client, sha256 string) (*graphql.Archive, error) {
+	slog.Debug("retrieving archive by sha256", slog.String("SHA256", sha256))
+	archive, err := graphql.GetArchiveBySha256(cmd.Context(), client, sha256)
+	if errors.Is(err, graphql.ErrArchiveNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error checking for an existing archive")
+	}
+	return archive, nil
+}
 
+// processedPart() waits until the catalog processed the archive with the sha256 and gives its part
+func processedPart(cmd *cobra.Command, client *graph.Client, sha256 string, options *uploadOptions) (*graphql.Part, error) {
+	ctx, cancel := context.WithTimeout(cmd.Context(), options.waitTimeout)
+	defer cancel()
+	part, err := graphql.WaitForPart(ctx, client, sha256, waitInterval, waitMaxInterval)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error waiting for the part of the archive")
+	}
+	return part, nil
+}
+
+// waitForPart() waits until the catalog processed the uploaded archive and prints its
+// part, after applying the part data and the profiles to it if there are any
+func waitForPart(cmd *cobra.Command, client *graph.Client, printer *output.Printer, sha256 string, options *uploadOptions, metadata *uploadMetadata) error {
+	printer.Info("Waiting for the catalog to process the archive")
+	part, err := processedPart(cmd, client, sha256, options)
+	if err != nil {
+		return err
+	}
+	if metadata != nil {
+		return applyMetadata(cmd, client, printer, part, metadata)
+	}
+	return printer.Print(part)
+}
+
+// applyMetadata() updates the part of an uploaded archive with the part data
+// and adds the profiles to it, the steps applied are reported as they complete
+func applyMetadata(cmd *cobra.Command, client *graph.Client, printer *output.Printer, part *graphql.Part, metadata *uploadMetadata) error {
+	partID := part.ID.String()
+	result := uploadMetadataResult{Part: part, Profiles: []addProfileResult{}}
+	if metadata.part != nil {
+		// the part of the archive is updated whatever the file identifies
+		metadata.part.CatalogID = partID
+		slog.Debug("updating part", slog.String("ID", partID))
+		updatedPart, err := graphql.UpdatePart(cmd.Context(), client, metadata.part)
+		if err != nil {
+			return errors.Wrapf(err, "error updating part %s with %s", partID, metadata.partPath)
+		}
+		printer.Info("Updated part %s with %s", partID, metadata.partPath)
+		result.Part = updatedPart
+	}
+	for _, profile := range metadata.profiles {
+		slog.Debug("adding profile", slog.String("ID", partID), slog.String("Key", profile.profile.Profile))
+		if err := graphql.AddProfile(cmd.Context(), client, partID, profile.profile.Profile, profile.document); err != nil {
+			return errors.Wrapf(err, "error adding %s profile of %s to part %s", profile.profile.Profile, profile.path, partID)
+		}
+		printer.Info("Added %s profile of %s to part %s", profile.profile.Profile, profile.path, partID)
+		result.Profiles = append(result.Profiles, addProfileResult{PartID: partID, Profile: profile.profile.Profile})
+	}
+	return printer.Print(result)
+}
+
+// uploadRecursive() uploads the selected files of the directory with a pool
+// of workers, prints the result of every file and a summary, and fails if
+// any of the files failed to upload
+func uploadRecursive(cmd *cobra.Command, configFile *config.ConfigData, client *graph.Client, dir string, options *uploadOptions) error {
+	paths, err := selectFiles(dir, options.include, options.exclude)
+	if err != nil {
+		return inputError(errors.Wrapf(err, "error reading directory %s", dir))
+	}
+	printer := newPrinter(cmd, configFile)
+	if !formatIsExplicit(cmd) {
+		printer.Format = output.Table
+	}
+	if options.quiet {
+		printer.Err = io.Discard
+	}
+	slog.Debug("uploading files", slog.String("Directory", dir), slog.Int("Files", len(paths)), slog.Int("Parallel", options.parallel))
+
+	results := make([]uploadFileResult, len(paths))
+	indexes := make(chan int)
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+	for i := 0; i < options.parallel; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				results[index] = uploadOne(cmd, configFile, client, paths[index], options)
+				// informational messages of the workers must not interleave
+				mutex.Lock()
+				if results[index].Status == uploadStatusFailed {
+					printer.Info("%s %s: %s", results[index].Status, results[index].Path, results[index].Error)
+				} else {
+					printer.Info("%s %s", results[index].Status, results[index].Path)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	// files are no longer handed to the workers once the command is cancelled
+	dispatched := 0
+dispatch:
+	for dispatched < len(paths) {
+		select {
+		case indexes <- dispatched:
+			dispatched++
+		case <-cmd.Context().Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	workers.Wait()
+	for i := dispatched; i < len(paths); i++ {
+		results[i] = uploadFileResult{Path: paths[i], Status: uploadStatusFailed, Error: cmd.Context().Err().Error()}
+	}
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	if err = printer.Print(results); err != nil {
+		return err
+	}
+	printer.Info("Uploaded %d, skipped %d, failed %d of %d files", counts[uploadStatusUploaded], counts[uploadStatusSkipped], counts[uploadStatusFailed], len(paths))
+	if err = cmd.Context().Err(); err != nil {
+		return err
+	}
+	if counts[uploadStatusFailed] > 0 {
+		return errors.Errorf("%d of %d files failed to upload", counts[uploadStatusFailed], len(paths))
+	}
+	return nil
+}
+
+// uploadOne() uploads a file of a recursive upload unless the catalog already has it
+func uploadOne(cmd *cobra.Command, configFile *config.ConfigData, client *graph.Client, path string, options *uploadOptions) uploadFileResult {
+	result := uploadFileResult{Path: path}
+	var sha256 string
+	var err error
+	if !options.force || options.wait {
+		if sha256, err = fileSha256(path); err != nil {
+			result.Status, result.Error = uploadStatusFailed, err.Error()
+			return result
+		}
+	}
+	if !options.force {
+		archive, err := existingArchive(cmd, client, sha256)
+		if err != nil {
+			result.Status, result.Error = uploadStatusFailed, err.Error()
+			return result
+		}
+		if archive != nil && (archive.PartID != uuid.Nil || !options.wait) {
+			result.Status = uploadStatusSkipped
+			// the catalog may not have assigned a part to the archive yet
+			if archive.PartID != uuid.Nil {
+				result.PartID = archive.PartID.String()
+			}
+			return result
+		}
+		if archive != nil {
+			result.Status = uploadStatusSkipped
+			return waitForResult(cmd, client, sha256, options, result)
+		}
+	}
+	slog.Debug("uploading file to server", slog.String("Path", path))
+	archive, err := graphql.UploadFile(cmd.Context(), http.DefaultClient, configFile.ServerAddr, path, "", options.uploadOptions(configFile))
+	if err != nil {
+		result.Status, result.Error = uploadStatusFailed, err.Error()
+		return result
+	}
+	result.Status = uploadStatusUploaded
+	// the catalog may assign the part after the upload
+	if archive.PartID != uuid.Nil {
+		result.PartID = archive.PartID.String()
+	}
+	if options.wait {
+		return waitForResult(cmd, client, sha256, options, result)
+	}
+	return result
+}
+
+// waitForResult() waits until the catalog processed the archive of the
+// result and sets its part id, or fails the result if it times out
+func waitForResult(cmd *cobra.Command, client *graph.Client, sha256 string, options *uploadOptions, result uploadFileResult) uploadFileResult {
+	part, err := processedPart(cmd, client, sha256, options)
+	if err != nil {
+		result.Status, result.Error = uploadStatusFailed, err.Error()
+		return result
+	}
+	result.PartID = part.ID.String()
+	return result
+}
+
+// selectFiles() gives the regular files in the directory and its sub directories
+// which match any of the include patterns, if there are any, and none of the
+// exclude patterns. Patterns are matched against the path relative to the
+// directory and against the name of the file.
+func selectFiles(dir string, include []string, exclude []string) ([]string, error) {
+	matches := func(patterns []string, relative string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, relative); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, filepath.Base(relative)); ok {
+				return true
+			}
+		}
+		return false
+	}
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if len(include) > 0 && !matches(include, relative) {
+			return nil
+		}
+		if matches(exclude, relative) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
 }
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package config
+
+import (
+	"bytes"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ContextNames() gives the sorted names of all the contexts in the configuration file
+func (configData *ConfigData) ContextNames() []string {
+	names := make([]string, 0, len(configData.Contexts))
+	for name := range configData.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergeContext() overlays the settings of the named context on top of the
+// top level settings read by viper, an empty name selects the current context.
+// A current context missing from the configuration file is not an error, the
+// top level settings are used instead so that another context can be selected,
+// and its name is given as missing.
+func mergeContext(name string) (string, string, error) {
+	if name == "" {
+		current := viper.GetString("current_context")
+		if current == "" {
+			return "", "", nil
+		}
+		if !viper.IsSet("contexts." + current) {
+			return "", current, nil
+		}
+		name = current
+	}
+	// check if the context is present in the configuration file
+	if !viper.IsSet("contexts." + name) {
+		return "", "", errors.Errorf("context %q not found in configuration file", name)
+	}
+	if err := viper.MergeConfigMap(viper.GetStringMap("contexts." + name)); err != nil {
+		return "", "", errors.Wrapf(err, "error reading context %q", name)
+	}
+	return name, "", nil
+}
+
+// SetCurrentContext() sets the current context of the configuration file
+// at the given path, leaving the rest of the file including its comments
+// untouched
+func SetCurrentContext(path string, name string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading config file")
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		return errors.Wrapf(err, "error decoding config file")
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return errors.New("error decoding config file, expected a mapping of settings")
+	}
+	// update the current context key if present, otherwise add it
+	mapping := document.Content[0]
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "current_context" {
+			mapping.Content[i+1].SetString(name)
+			found = true
+			break
+		}
+	}
+	if !found {
+		key := new(yaml.Node)
+		key.SetString("current_context")
+		value := new(yaml.Node)
+		value.SetString(name)
+		mapping.Content = append(mapping.Content, key, value)
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&document); err != nil {
+		return errors.Wrapf(err, "error encoding config file")
+	}
+	encoder.Close()
+	// keep the permissions of the existing file
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading config file")
+	}
+	if err = os.WriteFile(path, buffer.Bytes(), info.Mode().Perm()); err != nil {
+		return errors.Wrapf(err, "error writing config file")
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// environment variable which can point to the credentials file
+const CredentialsEnv = "CCLI_CREDENTIALS"
+
+// struct for storing the credentials file data, keyed by server address
+type Credentials struct {
+	Servers map[string]ServerCredentials `yaml:"servers"`
+}
+
+// struct for storing the credentials of a single catalog server, the
+// refresh token and expiry are only set for openid connect tokens
+type ServerCredentials struct {
+	Token        string    `yaml:"token"`
+	RefreshToken string    `yaml:"refresh_token,omitempty"`
+	Expiry       time.Time `yaml:"expiry,omitempty"`
+}
+
+// CredentialsPath() gives the path of the credentials file written by
+// ccli login, which is $XDG_CONFIG_HOME/ccli/credentials.yml unless set
+// by the CCLI_CREDENTIALS environment variable
+func CredentialsPath() (string, error) {
+	if envPath := os.Getenv(CredentialsEnv); envPath != "" {
+		return envPath, nil
+	}
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrapf(err, "error locating credentials file")
+		}
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfigHome, "ccli", "credentials.yml"), nil
+}
+
+// ReadCredentials() reads the credentials file, a missing file gives
+// empty credentials
+func ReadCredentials() (*Credentials, error) {
+	credentials := &Credentials{Servers: make(map[string]ServerCredentials)}
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return credentials, nil
+		}
+		return nil, errors.Wrapf(err, "error reading credentials file")
+	}
+	if err = yaml.Unmarshal(data, credentials); err != nil {
+		return nil, errors.Wrapf(err, "error decoding credentials file %s", path)
+	}
+	if credentials.Servers == nil {
+		credentials.Servers = make(map[string]ServerCredentials)
+	}
+	return credentials, nil
+}
+
+// Write() stores the credentials in the credentials file, which is only
+// readable and writable by the current user
+func (credentials *Credentials) Write() error {
+	path, err := CredentialsPath()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "error creating credentials directory")
+	}
+	data, err := yaml.Marshal(credentials)
+	if err != nil {
+		return errors.Wrapf(err, "error encoding credentials")
+	}
+	// write to a temporary file created with 0600 permissions and move it
+	// in place so a partially written file never replaces the old one
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".credentials-*.yml")
+	if err != nil {
+		return errors.Wrapf(err, "error creating credentials file")
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err = tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return errors.Wrapf(err, "error writing credentials file")
+	}
+	if err = tempFile.Close(); err != nil {
+		return errors.Wrapf(err, "error writing credentials file")
+	}
+	if err = os.Chmod(tempFile.Name(), 0600); err != nil {
+		return errors.Wrapf(err, "error setting credentials file permissions")
+	}
+	if err = os.Rename(tempFile.Name(), path); err != nil {
+		return errors.Wrapf(err, "error writing credentials file")
+	}
+	return nil
+}
+
+// StoreServerCredentials() updates the credentials of a single server in the credentials file
+func StoreServerCredentials(serverAddr string, serverCredentials ServerCredentials) error {
+	credentials, err := ReadCredentials()
+	if err != nil {
+		return err
+	}
+	credentials.Servers[serverAddr] = serverCredentials
+	return credentials.Write()
+}
+
+// ApplyCredentials() uses the token stored by ccli login for the configured
+// server if no credentials are set by the configuration, environment or flags.
+// Openid connect tokens are managed by the http package instead, since they
+// have to be refreshed.
+func (configData *ConfigData) ApplyCredentials() error {
+	if configData.Auth.IsSet() || configData.Auth.UsesOIDC() {
+		return nil
+	}
+	credentials, err := ReadCredentials()
+	if err != nil {
+		return err
+	}
+	if serverCredentials, ok := credentials.Servers[configData.ServerAddr]; ok {
+		configData.Auth.Token = serverCredentials.Token
+	}
+	return nil
+}
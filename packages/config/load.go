@@ -0,0 +1,201 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// environment variable which can point to a configuration file
+const ConfigEnv = "CCLI_CONFIG"
+
+// name of the configuration file looked up in the working directory
+const DefaultConfigFile = "ccli_config.yml"
+
+// time after which commands contacting the catalog are cancelled if not set otherwise
+const DefaultTimeout = 5 * time.Minute
+
+// retry policy used for settings not given by the configuration, mutations
+// are only retried if enabled explicitly
+var DefaultRetry = RetryConfig{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Jitter:         0.2,
+}
+
+// prefix of the environment variables overriding configuration keys,
+// e.g. CCLI_SERVER_ADDR overrides server_addr
+const EnvPrefix = "CCLI"
+
+// configuration keys which can be overridden by environment variables
+var EnvKeys = []string{
+	"server_addr", "log_file", "log_level", "json_indent", "format", "timeout",
+	"tls.ca_file", "tls.cert_file", "tls.key_file", "tls.server_name", "tls.min_version", "tls.insecure_skip_verify",
+	"auth.token", "auth.username", "auth.password", "auth.api_key", "auth.api_key_header",
+	"auth.oidc.issuer", "auth.oidc.client_id", "auth.oidc.client_secret", "auth.oidc.scopes",
+	"retry.max_attempts", "retry.initial_backoff", "retry.max_backoff", "retry.jitter", "retry.mutations",
+}
+
+// EnvName() gives the name of the environment variable overriding the given key
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// SearchPaths() gives the configuration file locations checked when
+// neither the config flag nor the environment variable is set, in order
+// of priority
+func SearchPaths() []string {
+	var paths []string
+	// XDG base directory, defaulting to ~/.config as per the specification
+	home, _ := os.UserHomeDir()
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" && home != "" {
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+	if xdgConfigHome != "" {
+		paths = append(paths, filepath.Join(xdgConfigHome, "ccli", "config.yml"))
+	}
+	// dot file in the home directory
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".ccli.yml"))
+	}
+	// configuration file in the current working directory
+	paths = append(paths, DefaultConfigFile)
+	return paths
+}
+
+// ConfigPath() resolves the configuration file to be used. The path given
+// through the config flag wins, followed by the CCLI_CONFIG environment
+// variable and then the first existing file from SearchPaths(). An empty
+// path is returned if no file is found, in which case the configuration
+// has to come from environment variables and flags.
+func ConfigPath(flagPath string) (string, error) {
+	// an explicitly requested file has to be present
+	if flagPath != "" {
+		if _, err := os.Stat(flagPath); err != nil {
+			return "", errors.Wrapf(err, "error reading config file given by --config")
+		}
+		return flagPath, nil
+	}
+	if envPath := os.Getenv(ConfigEnv); envPath != "" {
+		if _, err := os.Stat(envPath); err != nil {
+			return "", errors.Wrapf(err, "error reading config file given by %s", ConfigEnv)
+		}
+		return envPath, nil
+	}
+	// look for the first configuration file present in the search chain
+	searchPaths := SearchPaths()
+	for _, path := range searchPaths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// Load() reads the configuration file at the given path, unmarshals it into
+// the config data struct and validates the values read. The settings of the
+// named context, or the current context if no name is given, take precedence
+// over the top level settings of the file. Environment variables and flags
+// bound to viper take precedence over both.
+func (configData *ConfigData) Load(path string, contextName string) error {
+	// defaults matching ccli_config.DEFAULT.yml for running without a config file
+	viper.SetDefault("log_file", "log.txt")
+	viper.SetDefault("log_level", 1)
+	viper.SetDefault("json_indent", 2)
+	viper.SetDefault("format", "json")
+	viper.SetDefault("timeout", DefaultTimeout)
+	viper.SetDefault("retry.max_attempts", DefaultRetry.MaxAttempts)
+	viper.SetDefault("retry.initial_backoff", DefaultRetry.InitialBackoff)
+	viper.SetDefault("retry.max_backoff", DefaultRetry.MaxBackoff)
+	viper.SetDefault("retry.jitter", DefaultRetry.Jitter)
+	// bind the environment variables overriding the configuration keys
+	for _, key := range EnvKeys {
+		if err := viper.BindEnv(key, EnvName(key)); err != nil {
+			return errors.Wrapf(err, "error binding environment variable %s", EnvName(key))
+		}
+	}
+	// set the config file and read it
+	if path != "" {
+		viper.SetConfigFile(path)
+		if err := viper.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "error reading config file %s", path)
+		}
+	}
+	contextName, missingContext, err := mergeContext(contextName)
+	if err != nil {
+		return err
+	}
+	// unmarshal the config file parameters to a struct
+	if err := viper.Unmarshal(configData); err != nil {
+		return errors.Wrapf(err, "could not unmarshal config file parameters")
+	}
+	configData.Path = path
+	configData.Context = contextName
+	configData.MissingContext = missingContext
+	return configData.Validate()
+}
+
+// Validate() checks if the configuration values are usable, the server
+// address is checked separately by ValidateServer() since it is only
+// needed by commands contacting the catalog
+func (configData *ConfigData) Validate() error {
+	// check if the log file is present and has the correct extension
+	if filepath.Ext(configData.LogFile) != ".txt" {
+		return errors.New("error reading config file, log file must be a .txt file")
+	}
+	// check if the log level is accurate
+	if configData.LogLevel > 2 || configData.LogLevel < 1 {
+		return errors.New("error reading log level, log level must be either 1 or 2")
+	}
+	// check if the timeout is either disabled or positive
+	if configData.Timeout < 0 {
+		return errors.New("error reading timeout, timeout must not be negative")
+	}
+	// check if the retry policy is usable
+	if configData.Retry.MaxAttempts < 1 {
+		return errors.New("error reading retry policy, max_attempts must be at least 1")
+	}
+	if configData.Retry.InitialBackoff < 0 || configData.Retry.MaxBackoff < 0 {
+		return errors.New("error reading retry policy, backoff must not be negative")
+	}
+	if configData.Retry.Jitter < 0 || configData.Retry.Jitter > 1 {
+		return errors.New("error reading retry policy, jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// ValidateServer() checks if a server address is provided
+func (configData *ConfigData) ValidateServer() error {
+	if configData.ServerAddr == "" {
+		if configData.Path == "" {
+			return errors.Errorf("no server address located. Please create a configuration file in any of: %s by copying the contents of ccli_config.DEFAULT.yml, or set %s or --server.", strings.Join(SearchPaths(), ", "), EnvName("server_addr"))
+		}
+		return errors.New("invalid configuration file, no server address located")
+	}
+	return nil
+}
+
+// Indent() gives the json indentation string for the configured json indent
+func (configData *ConfigData) Indent() string {
+	if configData.JsonIndent < 1 {
+		return ""
+	}
+	return strings.Repeat(" ", int(configData.JsonIndent))
+}
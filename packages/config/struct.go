@@ -15,16 +15,106 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 // struct for storing config file data
 type ConfigData struct {
-	ServerAddr string `mapstructure:"server_addr"`
-	LogFile    string `mapstructure:"log_file"`
-	LogLevel   int64  `mapstructure:"log_level"`
-	JsonIndent int64  `mapstructure:"json_indent"`
+	ServerAddr     string             `mapstructure:"server_addr"`
+	LogFile        string             `mapstructure:"log_file"`
+	LogLevel       int64              `mapstructure:"log_level"`
+	JsonIndent     int64              `mapstructure:"json_indent"`
+	Format         string             `mapstructure:"format"`
+	Timeout        time.Duration      `mapstructure:"timeout"`
+	TLS            TLSConfig          `mapstructure:"tls"`
+	Auth           AuthConfig         `mapstructure:"auth"`
+	Retry          RetryConfig        `mapstructure:"retry"`
+	CurrentContext string             `mapstructure:"current_context"`
+	Contexts       map[string]Context `mapstructure:"contexts"`
+	// path of the configuration file the data was read from
+	Path string `mapstructure:"-"`
+	// name of the context the settings were taken from, if any
+	Context string `mapstructure:"-"`
+	// name of the current context if it is not in the configuration file
+	MissingContext string `mapstructure:"-"`
+}
+
+// struct for storing a named server profile, any value set
+// overrides the top level value of the config file
+type Context struct {
+	ServerAddr string        `mapstructure:"server_addr"`
+	LogFile    string        `mapstructure:"log_file"`
+	LogLevel   int64         `mapstructure:"log_level"`
+	JsonIndent int64         `mapstructure:"json_indent"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	TLS        TLSConfig     `mapstructure:"tls"`
+	Auth       AuthConfig    `mapstructure:"auth"`
+	Retry      RetryConfig   `mapstructure:"retry"`
+}
+
+// struct for storing the tls settings used to connect to the catalog
+type TLSConfig struct {
+	// pem bundle of certificate authorities trusted in addition to the system pool
+	CAFile string `mapstructure:"ca_file"`
+	// client certificate and key for mutual tls
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// server name used to verify the certificate instead of the host name
+	ServerName string `mapstructure:"server_name"`
+	// minimum tls version, e.g. "1.2" or "1.3"
+	MinVersion string `mapstructure:"min_version"`
+	// disable certificate verification, only for testing against self signed servers
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+}
+
+// struct for storing the credentials sent to the catalog, a bearer token
+// takes precedence over basic auth which takes precedence over an api key
+type AuthConfig struct {
+	Token    string `mapstructure:"token"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	APIKey   string `mapstructure:"api_key"`
+	// header carrying the api key, defaults to X-API-Key
+	APIKeyHeader string `mapstructure:"api_key_header"`
+	// openid connect provider issuing the tokens, used if none of the above is set
+	OIDC OIDCConfig `mapstructure:"oidc"`
+}
+
+// struct for storing the openid connect client settings, the client secret
+// is only needed for the client credentials grant
+type OIDCConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// struct for storing the policy for retrying requests which failed with a
+// transient error, i.e. a connection error or a 429, 502, 503 or 504 response
+type RetryConfig struct {
+	// number of attempts per request, 1 disables retries
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// delay before the first retry, doubled for every further retry up to max_backoff
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	// fraction of the delay randomly added or subtracted, between 0 and 1
+	Jitter float64 `mapstructure:"jitter"`
+	// also retry mutations, which may apply a mutation twice if
+	// the server received it but the response was lost
+	Mutations bool `mapstructure:"mutations"`
+}
+
+// IsSet() checks if any static credentials are configured
+func (auth AuthConfig) IsSet() bool {
+	return auth.Token != "" || auth.Username != "" || auth.APIKey != ""
+}
+
+// UsesOIDC() checks if tokens are to be obtained from an openid connect
+// provider, which is only the case if no static credentials are set
+func (auth AuthConfig) UsesOIDC() bool {
+	return !auth.IsSet() && auth.OIDC.Issuer != ""
 }
 
 // struct for storing io.writer
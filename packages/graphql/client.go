@@ -17,7 +17,8 @@ import (
 	"github.com/hasura/go-graphql-client"
 )
 
-// port hasura client generation into ccli graphql package
+// port hasura client generation into ccli graphql package, the requests
+// are recorded to give typed errors for failed queries and mutations
 func GetNewClient(url string, httpClient graphql.Doer) *graphql.Client {
-	return graphql.NewClient(url, httpClient)
+	return graphql.NewClient(url, &recordingDoer{doer: httpClient})
 }
@@ -15,8 +15,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
+	"time"
+	"wrs/catalog/ccli/packages/checksum"
 	"wrs/catalog/ccli/packages/yaml"
 
 	graphqlUpload "bitbucket.wrs.com/scm/weststar/graphql-upload-go.git"
@@ -36,7 +41,7 @@ func AddProfile(ctx context.Context, client *graphql.Client, id string, key stri
 		"key":      key,
 		"document": JSON(document),
 	}
-	if err := client.Mutate(ctx, &mutation, variables); err != nil {
+	if err := runMutation(ctx, client, &mutation, variables); err != nil {
 		return err
 	}
 	return nil
@@ -54,7 +59,7 @@ func DeletePart(ctx context.Context, client *graphql.Client, id string, recursiv
 		"forceDelete":     forceDelete,
 	}
 
-	if err := client.Mutate(ctx, &mutation, variables); err != nil {
+	if err := runMutation(ctx, client, &mutation, variables); err != nil {
 		return err
 	}
 	return nil
@@ -71,15 +76,17 @@ func GetProfile(ctx context.Context, client *graphql.Client, id string, key stri
 		"key": key,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
 
 	return &query.Profile, nil
 }
 
-// Adds a logical part to the catalog using a yaml template format and returns the inserted part
-func AddPart(ctx context.Context, client *graphql.Client, newPart yaml.Part) (*Part, error) {
+// Adds a logical part to the catalog using a yaml template format and returns the inserted part.
+// The entries of the composite list are the ids of the subparts, unless children gives the
+// id of the subpart of an entry, in which case the entry is kept as the path of the subpart.
+func AddPart(ctx context.Context, client *graphql.Client, newPart yaml.Part, children map[string]string, rollback bool) (*Part, error) {
 	var newPartInput NewPartInput
 
 	if err := YamlToNewPartInput(newPart, &newPartInput); err != nil {
@@ -94,10 +101,18 @@ func AddPart(ctx context.Context, client *graphql.Client, newPart yaml.Part) (*P
 		"partInput": newPartInput,
 	}
 
-	if err := client.Mutate(ctx, &mutation, variables); err != nil {
+	if err := runMutation(ctx, client, &mutation, variables); err != nil {
 		return nil, err
 	}
 
+	// every applied mutation is recorded so it can be compensated if a later one fails
+	var applied journal
+	partID := mutation.Part.ID.String()
+	applied.record("createPart "+partID, "deletePart "+partID, func(ctx context.Context, client *graphql.Client) error {
+		// the links to the subparts are removed with the part, the subparts themselves are kept
+		return DeletePart(ctx, client, partID, false, true)
+	})
+
 	//Alias insertion is handling with createAlias mutation
 	if newPart.Aliases != nil && len(newPart.Aliases) != 0 {
 		var aliasMutation struct {
@@ -106,14 +121,15 @@ func AddPart(ctx context.Context, client *graphql.Client, newPart yaml.Part) (*P
 
 		for _, v := range newPart.Aliases {
 			aliasVariables := map[string]interface{}{
-				"id":    UUID(mutation.Part.ID.String()),
+				"id":    UUID(partID),
 				"alias": v,
 			}
 
-			if err := client.Mutate(ctx, &aliasMutation, aliasVariables); err != nil {
-				return nil, err
+			if err := runMutation(ctx, client, &aliasMutation, aliasVariables); err != nil {
+				return nil, applied.fail(ctx, client, err, rollback)
 			}
-
+			// aliases are compensated by deleting the part they refer to
+			applied.record("createAlias "+v, "", nil)
 		}
 	}
 	// Subparts are inserted utilizing partHasPart mutation
@@ -134,15 +150,21 @@ func AddPart(ctx context.Context, client *graphql.Client, newPart yaml.Part) (*P
 		}
 
 		for _, v := range compositeList {
+			child := v
+			if id, ok := children[v]; ok {
+				child = id
+			}
 			compositeVariables := map[string]interface{}{
-				"parent": UUID(mutation.ID.String()),
-				"child":  UUID(v),
+				"parent": UUID(partID),
+				"child":  UUID(child),
 				"path":   v,
 			}
 
-			if err := client.Mutate(ctx, &compositeMutation, compositeVariables); err != nil {
-				return nil, err
+			if err := runMutation(ctx, client, &compositeMutation, compositeVariables); err != nil {
+				return nil, applied.fail(ctx, client, err, rollback)
 			}
+			// links are compensated by deleting the part
+			applied.record("partHasPart "+v, "", nil)
 		}
 	}
 
@@ -159,9 +181,12 @@ func GetPartIDBySha256(ctx context.Context, client *graphql.Client, sha256 strin
 		"sha256": sha256,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
+	if query.PartID == uuid.Nil {
+		return nil, errors.Wrapf(ErrPartNotFound, "no part with sha256 %s", sha256)
+	}
 
 	return &query.PartID, nil
 }
@@ -176,13 +201,100 @@ func GetPartIDByFVC(ctx context.Context, client *graphql.Client, fvc string) (*u
 		"fvc": fvc,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
+	if query.Part.ID == uuid.Nil {
+		return nil, errors.Wrapf(ErrPartNotFound, "no part with file verification code %s", fvc)
+	}
 
 	return &query.Part.ID, nil
 }
 
+// Retrieves an archive and its part from the catalog using sha256
+func GetArchiveBySha256(ctx context.Context, client *graphql.Client, sha256 string) (*Archive, error) {
+
+	var query struct {
+		Archive `graphql:"archive(sha256: $sha256)"`
+	}
+
+	variables := map[string]interface{}{
+		"sha256": sha256,
+	}
+
+	if err := runQuery(ctx, client, &query, variables); err != nil {
+		return nil, err
+	}
+	if query.Archive.Sha256 == "" {
+		return nil, errors.Wrapf(ErrArchiveNotFound, "no archive with sha256 %s", sha256)
+	}
+	return &query.Archive, nil
+}
+
+// Retrieves an archive and its part from the catalog using sha1
+func GetArchiveBySha1(ctx context.Context, client *graphql.Client, sha1 string) (*Archive, error) {
+
+	var query struct {
+		Archive `graphql:"archive(sha1: $sha1)"`
+	}
+
+	variables := map[string]interface{}{
+		"sha1": sha1,
+	}
+
+	if err := runQuery(ctx, client, &query, variables); err != nil {
+		return nil, err
+	}
+	if query.Archive.Sha256 == "" {
+		return nil, errors.Wrapf(ErrArchiveNotFound, "no archive with sha1 %s", sha1)
+	}
+	return &query.Archive, nil
+}
+
+// Retrieves an archive and its part from the catalog using md5
+func GetArchiveByMd5(ctx context.Context, client *graphql.Client, md5 string) (*Archive, error) {
+
+	var query struct {
+		Archive `graphql:"archive(md5: $md5)"`
+	}
+
+	variables := map[string]interface{}{
+		"md5": md5,
+	}
+
+	if err := runQuery(ctx, client, &query, variables); err != nil {
+		return nil, err
+	}
+	if query.Archive.Sha256 == "" {
+		return nil, errors.Wrapf(ErrArchiveNotFound, "no archive with md5 %s", md5)
+	}
+	return &query.Archive, nil
+}
+
+// Waits until the catalog has processed the archive with the given sha256 and
+// retrieves its part. The catalog is polled with the interval doubling up to
+// maxInterval until the part is available or the context is done.
+func WaitForPart(ctx context.Context, client *graphql.Client, sha256 string, interval time.Duration, maxInterval time.Duration) (*Part, error) {
+	for {
+		archive, err := GetArchiveBySha256(ctx, client, sha256)
+		if err != nil && !errors.Is(err, ErrArchiveNotFound) {
+			return nil, err
+		}
+		if err == nil && archive.PartID != uuid.Nil {
+			return GetPartByID(ctx, client, archive.PartID.String())
+		}
+		slog.Debug("waiting for the archive to be processed", slog.String("SHA256", sha256), slog.Duration("Interval", interval))
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "archive with sha256 %s was not processed in time", sha256)
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
 // Retrieves a part from the catalog using catalog id
 func GetPartByID(ctx context.Context, client *graphql.Client, id string) (*Part, error) {
 
@@ -194,9 +306,12 @@ func GetPartByID(ctx context.Context, client *graphql.Client, id string) (*Part,
 		"id": UUID(id),
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
+	if query.Part.ID == uuid.Nil {
+		return nil, errors.Wrapf(ErrPartNotFound, "no part with id %s", id)
+	}
 	return &query.Part, nil
 }
 
@@ -211,9 +326,12 @@ func GetPartByFVC(ctx context.Context, client *graphql.Client, fvc string) (*Par
 		"fvc": fvc,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
+	if query.Part.ID == uuid.Nil {
+		return nil, errors.Wrapf(ErrPartNotFound, "no part with file verification code %s", fvc)
+	}
 	return &query.Part, nil
 }
 
@@ -228,14 +346,139 @@ func GetPartBySHA256(ctx context.Context, client *graphql.Client, sha256 string)
 		"sha256": sha256,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
+	if query.Part.ID == uuid.Nil {
+		return nil, errors.Wrapf(ErrPartNotFound, "no part with sha256 %s", sha256)
+	}
 	return &query.Part, nil
 }
 
-// Retrieves a slice of parts from the catalog using find_archive query to search by name
-func Search(ctx context.Context, client *graphql.Client, searchQuery string) (*[]Part, error) {
+// search methods of the find_archive query
+var SearchMethods = []string{"fast", "exact", "fuzzy"}
+
+// SearchOptions select the search method and the page of the
+// search results, and filter them by the fields of the parts
+type SearchOptions struct {
+	Method string
+	// maximum number of parts in the page, 0 for all of them
+	Limit  int
+	Offset int
+	// filters matching the fields ignoring case, the license
+	// filter matches any license of a license expression
+	Type        string
+	ContentType string
+	License     string
+	FamilyName  string
+}
+
+// SearchPage is a page of the sorted search results
+type SearchPage struct {
+	Parts []Part
+	// archives found for each part of the page by part id, oldest first
+	Archives map[uuid.UUID][]Archive
+	// number of parts matching the search and the filters
+	Total int
+	// offset of the next page, 0 if this is the last one
+	NextOffset int
+}
+
+// ArchivePage is a page of the sorted archive search results
+type ArchivePage struct {
+	Archives []Archive
+	// number of archives matching the search and the filters
+	Total int
+	// offset of the next page, 0 if this is the last one
+	NextOffset int
+}
+
+// Retrieves a page of parts from the catalog using find_archive query to search by name.
+// Parts are deduplicated, filtered and sorted by name, version and id before paging.
+func Search(ctx context.Context, client *graphql.Client, searchQuery string, options SearchOptions) (*SearchPage, error) {
+	archives, err := findArchives(ctx, client, searchQuery, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	// several archives of the same part are found as one part,
+	// archives which do not belong to a part are left out
+	archivesByPart := make(map[uuid.UUID][]Archive)
+	parts := []Part{}
+	for _, archive := range archives {
+		if archive.Part.ID == uuid.Nil {
+			continue
+		}
+		if _, ok := archivesByPart[archive.Part.ID]; !ok {
+			parts = append(parts, archive.Part)
+		}
+		archivesByPart[archive.Part.ID] = append(archivesByPart[archive.Part.ID], archive)
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].Name != parts[j].Name {
+			return parts[i].Name < parts[j].Name
+		}
+		if parts[i].Version != parts[j].Version {
+			return parts[i].Version < parts[j].Version
+		}
+		return parts[i].ID.String() < parts[j].ID.String()
+	})
+
+	page := &SearchPage{Total: len(parts), Archives: make(map[uuid.UUID][]Archive)}
+	start, end := options.bounds(len(parts))
+	if end < len(parts) {
+		page.NextOffset = end
+	}
+	page.Parts = parts[start:end]
+	for _, part := range page.Parts {
+		partArchives := archivesByPart[part.ID]
+		sort.SliceStable(partArchives, func(i, j int) bool {
+			return partArchives[i].InsertDate < partArchives[j].InsertDate
+		})
+		page.Archives[part.ID] = partArchives
+	}
+	return page, nil
+}
+
+// Retrieves a page of archives from the catalog using find_archive query to search by name.
+// Archives are filtered by their part and sorted by name and insert date before paging.
+func SearchArchives(ctx context.Context, client *graphql.Client, searchQuery string, options SearchOptions) (*ArchivePage, error) {
+	archives, err := findArchives(ctx, client, searchQuery, &options)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		if archives[i].Name != archives[j].Name {
+			return archives[i].Name < archives[j].Name
+		}
+		if archives[i].InsertDate != archives[j].InsertDate {
+			return archives[i].InsertDate < archives[j].InsertDate
+		}
+		return archives[i].Sha256 < archives[j].Sha256
+	})
+
+	page := &ArchivePage{Total: len(archives)}
+	start, end := options.bounds(len(archives))
+	if end < len(archives) {
+		page.NextOffset = end
+	}
+	page.Archives = archives[start:end]
+	return page, nil
+}
+
+// findArchives() runs the find_archive query and gives the archives
+// found whose parts pass the filters of the search
+func findArchives(ctx context.Context, client *graphql.Client, searchQuery string, options *SearchOptions) ([]Archive, error) {
+	method := options.Method
+	if method == "" {
+		method = SearchMethods[0]
+	}
+	if !IsSearchMethod(method) {
+		return nil, errors.Errorf("invalid search method %q, must be one of %s", method, strings.Join(SearchMethods, ", "))
+	}
+	if options.Limit < 0 || options.Offset < 0 {
+		return nil, errors.New("search limit and offset must not be negative")
+	}
 
 	var query struct {
 		FindArchive []struct {
@@ -245,23 +488,73 @@ func Search(ctx context.Context, client *graphql.Client, searchQuery string) (*[
 
 	variables := map[string]interface{}{
 		"searchQuery": searchQuery,
-		"method":      "fast",
+		"method":      method,
 	}
 
-	if err := client.Query(ctx, &query, variables); err != nil {
+	if err := runQuery(ctx, client, &query, variables); err != nil {
 		return nil, err
 	}
 
-	var parts []Part
+	archives := []Archive{}
 	for _, v := range query.FindArchive {
-		parts = append(parts, v.Part)
+		if options.matches(&v.Part) {
+			archives = append(archives, v.Archive)
+		}
+	}
+	return archives, nil
+}
+
+// bounds() gives the start and end of the page within the search results
+func (options *SearchOptions) bounds(total int) (int, int) {
+	if options.Offset >= total {
+		return total, total
+	}
+	end := total
+	if options.Limit > 0 && options.Offset+options.Limit < end {
+		end = options.Offset + options.Limit
+	}
+	return options.Offset, end
+}
+
+// IsSearchMethod() checks if the method is supported by find_archive
+func IsSearchMethod(method string) bool {
+	for _, m := range SearchMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// matches() checks if the part passes the filters of the search
+func (options *SearchOptions) matches(part *Part) bool {
+	if options.Type != "" && !strings.EqualFold(part.PartType, options.Type) {
+		return false
+	}
+	if options.ContentType != "" && !strings.EqualFold(part.ContentType, options.ContentType) {
+		return false
+	}
+	if options.FamilyName != "" && !strings.EqualFold(part.FamilyName, options.FamilyName) {
+		return false
+	}
+	if options.License != "" {
+		// split expressions such as "(MIT OR Apache-2.0) AND BSD-3-Clause" into their licenses
+		licenses := strings.FieldsFunc(part.License, func(r rune) bool {
+			return r == ' ' || r == '(' || r == ')'
+		})
+		for _, license := range licenses {
+			if strings.EqualFold(license, options.License) {
+				return true
+			}
+		}
+		return false
 	}
-	return &parts, nil
+	return true
 }
 
 // allow user defined queries to be executed by ccli
 func Query(ctx context.Context, client *graphql.Client, query string) ([]byte, error) {
-	response, err := client.ExecRaw(ctx, query, nil)
+	response, err := runRaw(ctx, client, query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -269,14 +562,60 @@ func Query(ctx context.Context, client *graphql.Client, query string) ([]byte, e
 	return response, nil
 }
 
-// uploads an archive to the catalog using graphql-upload library
-func UploadFile(httpClient *http.Client, uri string, path string, name string) (*graphqlUpload.Response, error) {
+// uploads an archive to the catalog using graphql-upload library, or in resumable
+// chunks if the catalog supports it, and gives the archive created by the catalog.
+// The upload is aborted once the given context is cancelled. Errors of the catalog
+// are given as a GraphQLError.
+func UploadFile(ctx context.Context, httpClient *http.Client, uri string, path string, name string, options UploadOptions) (*Archive, error) {
+	var response *graphqlUpload.Response
+	var err error
+	// archives are uploaded in resumable chunks if the server supports it
+	if resumable(ctx, httpClient, uri) {
+		slog.Debug("uploading file in resumable chunks", slog.String("Path", path))
+		response, err = uploadResumable(ctx, httpClient, uri, path, options)
+		if err == nil && response == nil {
+			return completedArchive(ctx, httpClient, uri, path)
+		}
+	} else {
+		response, err = uploadMultipart(ctx, httpClient, uri, path, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = uploadError(response.Errors); err != nil {
+		return nil, err
+	}
+	return uploadedArchive(response)
+}
+
+// completedArchive() retrieves the archive of a resumable upload the
+// server completed without a graphql response by the sha256 of the file
+func completedArchive(ctx context.Context, httpClient *http.Client, uri string, path string) (*Archive, error) {
+	sha256, err := checksum.FileSha256(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error computing sha256 of %s", path)
+	}
+	slog.Debug("retrieving uploaded archive by sha256", slog.String("SHA256", sha256))
+	archive, err := GetArchiveBySha256(ctx, GetNewClient(uri, httpClient), sha256)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the uploaded archive")
+	}
+	return archive, nil
+}
+
+// uploadMultipart() uploads the archive in a single multipart request
+func uploadMultipart(ctx context.Context, httpClient *http.Client, uri string, path string, options UploadOptions) (*graphqlUpload.Response, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+	// the upload library does not take a context, so it is
+	// attached to its requests by the http client instead
+	contextClient := *httpClient
+	contextClient.Transport = &contextTransport{ctx: ctx, base: httpClient.Transport}
 	response, err := graphqlUpload.Upload(
-		http.DefaultClient,
+		&contextClient,
 		uri,
 		`
 		mutation($file: Upload!){
@@ -292,15 +631,64 @@ func UploadFile(httpClient *http.Client, uri string, path string, name string) (
 		graphqlUpload.File{
 			Name:     path,
 			Variable: "file",
-			Data:     f,
+			Data:     &progressReader{reader: f, progress: options.Progress},
 		},
 	)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &RequestError{Err: err}
 	}
 	return response, nil
 }
 
+// uploadedArchive() gives the archive of the data of the upload response
+func uploadedArchive(response *graphqlUpload.Response) (*Archive, error) {
+	var data struct {
+		UploadArchive *struct {
+			Name       string    `json:"name"`
+			InsertDate string    `json:"insert_date"`
+			Sha256     string    `json:"sha256"`
+			Sha1       string    `json:"sha1"`
+			PartID     uuid.UUID `json:"part_id"`
+		} `json:"uploadArchive"`
+	}
+	encoded, err := json.Marshal(response.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading upload response")
+	}
+	if err = json.Unmarshal(encoded, &data); err != nil {
+		return nil, errors.Wrapf(err, "error reading upload response")
+	}
+	if data.UploadArchive == nil {
+		return nil, errors.New("upload response has no archive")
+	}
+	return &Archive{
+		Name:       data.UploadArchive.Name,
+		InsertDate: data.UploadArchive.InsertDate,
+		Sha256:     data.UploadArchive.Sha256,
+		Sha1:       data.UploadArchive.Sha1,
+		PartID:     data.UploadArchive.PartID,
+	}, nil
+}
+
+// contextTransport is a http.RoundTripper sending every request
+// with the given context through the base transport
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (transport *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := transport.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req.WithContext(transport.ctx))
+}
+
 // updates a part record from the catalog using yaml template
 func UpdatePart(ctx context.Context, client *graphql.Client, partData *yaml.Part) (*Part, error) {
 
@@ -314,19 +702,15 @@ func UpdatePart(ctx context.Context, client *graphql.Client, partData *yaml.Part
 			if err != nil {
 				return nil, err
 			}
-			if catalogID != nil {
-				partInputID := UUID(catalogID.String())
-				partInput.ID = &partInputID
-			}
+			partInputID := UUID(catalogID.String())
+			partInput.ID = &partInputID
 		} else if partData.Sha256 != "" {
 			catalogID, err := GetPartIDBySha256(ctx, client, partData.Sha256)
 			if err != nil {
 				return nil, err
 			}
-			if catalogID != nil {
-				partInputID := UUID(catalogID.String())
-				partInput.ID = &partInputID
-			}
+			partInputID := UUID(catalogID.String())
+			partInput.ID = &partInputID
 		}
 	}
 	if partData.CatalogID != "" {
@@ -376,7 +760,7 @@ func UpdatePart(ctx context.Context, client *graphql.Client, partData *yaml.Part
 		"partInput": partInput,
 	}
 
-	if err := client.Mutate(ctx, &mutation, variables); err != nil {
+	if err := runMutation(ctx, client, &mutation, variables); err != nil {
 		return nil, err
 	}
 
@@ -391,7 +775,7 @@ func UpdatePart(ctx context.Context, client *graphql.Client, partData *yaml.Part
 				"alias": v,
 			}
 
-			if err := client.Mutate(ctx, &aliasMutation, aliasVariables); err != nil {
+			if err := runMutation(ctx, client, &aliasMutation, aliasVariables); err != nil {
 				return nil, err
 			}
 		}
@@ -0,0 +1,151 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"wrs/catalog/ccli/packages/yaml"
+
+	"github.com/pkg/errors"
+)
+
+// id of the parts created by the catalog stand-in
+const standInPartID = "0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"
+
+// standInOperation is a mutation received by the catalog stand-in
+type standInOperation struct {
+	name      string
+	variables map[string]interface{}
+}
+
+// catalogStandIn is a graphql server answering the mutations of adding
+// a part, which fails the mutations of the given names
+type catalogStandIn struct {
+	mutex      sync.Mutex
+	operations []standInOperation
+	fail       map[string]bool
+	server     *httptest.Server
+}
+
+func newCatalogStandIn(tester *testing.T, fail ...string) *catalogStandIn {
+	standIn := &catalogStandIn{fail: make(map[string]bool)}
+	for _, name := range fail {
+		standIn.fail[name] = true
+	}
+	standIn.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			tester.Errorf("Expected a graphql request but got %v", err)
+		}
+		// the name of the mutation is the first field of the selection
+		name := body.Query[strings.IndexByte(body.Query, '{')+1:]
+		name = name[:strings.IndexAny(name, "({")]
+		standIn.mutex.Lock()
+		standIn.operations = append(standIn.operations, standInOperation{name: name, variables: body.Variables})
+		standIn.mutex.Unlock()
+		if standIn.fail[name] {
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]string{{"message": name + " failed"}}})
+			return
+		}
+		var data interface{} = true
+		switch name {
+		case "createPart":
+			data = map[string]interface{}{"id": standInPartID}
+		case "createAlias":
+			data = standInPartID
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{name: data}})
+	}))
+	tester.Cleanup(standIn.server.Close)
+	return standIn
+}
+
+// names() gives the names of the mutations received in order
+func (standIn *catalogStandIn) names() []string {
+	standIn.mutex.Lock()
+	defer standIn.mutex.Unlock()
+	var names []string
+	for _, operation := range standIn.operations {
+		names = append(names, operation.name)
+	}
+	return names
+}
+
+// TestAddPartChildren checks that subparts given by their ids are linked with
+// the entry of the composite list as their path
+func TestAddPartChildren(tester *testing.T) {
+	standIn := newCatalogStandIn(tester)
+	client := GetNewClient(standIn.server.URL, standIn.server.Client())
+	childID := "1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"
+	part := yaml.Part{Name: "sdk", CompositeList: []string{"zlib-1.3", childID}}
+	if _, err := AddPart(context.Background(), client, part, map[string]string{"zlib-1.3": "2b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"}, true); err != nil {
+		tester.Fatal("failed to add part", err)
+	}
+	var links []map[string]interface{}
+	for _, operation := range standIn.operations {
+		if operation.name == "partHasPart" {
+			links = append(links, operation.variables)
+		}
+	}
+	expected := []map[string]interface{}{
+		{"parent": standInPartID, "child": "2b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f", "path": "zlib-1.3"},
+		{"parent": standInPartID, "child": childID, "path": childID},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		tester.Errorf("Expected the links %v but got %v", expected, links)
+	}
+}
+
+// TestAddPartRollback checks that a part whose link fails after its aliases
+// were created is deleted again and the applied steps are reported
+func TestAddPartRollback(tester *testing.T) {
+	standIn := newCatalogStandIn(tester, "partHasPart")
+	client := GetNewClient(standIn.server.URL, standIn.server.Client())
+	part := yaml.Part{Name: "sdk", Aliases: []string{"sdk-2.0", "sdk-latest"}, CompositeList: []string{"1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"}}
+	_, err := AddPart(context.Background(), client, part, nil, true)
+	var partialErr *PartialMutationError
+	if !errors.As(err, &partialErr) {
+		tester.Fatalf("Expected a partial mutation error but got %v", err)
+	}
+	expectedApplied := []string{"createPart " + standInPartID, "createAlias sdk-2.0", "createAlias sdk-latest"}
+	if !reflect.DeepEqual(partialErr.Applied, expectedApplied) {
+		tester.Errorf("Expected the applied mutations %v but got %v", expectedApplied, partialErr.Applied)
+	}
+	if expected := []string{"deletePart " + standInPartID}; !reflect.DeepEqual(partialErr.RolledBack, expected) || partialErr.RollbackErr != nil {
+		tester.Errorf("Expected the rollback %v but got %v, %v", expected, partialErr.RolledBack, partialErr.RollbackErr)
+	}
+	expectedNames := []string{"createPart", "createAlias", "createAlias", "partHasPart", "deletePart"}
+	if names := standIn.names(); !reflect.DeepEqual(names, expectedNames) {
+		tester.Errorf("Expected the mutations %v but got %v", expectedNames, names)
+	}
+
+	// without rollback the part is kept
+	standIn = newCatalogStandIn(tester, "createAlias")
+	client = GetNewClient(standIn.server.URL, standIn.server.Client())
+	_, err = AddPart(context.Background(), client, part, nil, false)
+	if !errors.As(err, &partialErr) || partialErr.RolledBack != nil {
+		tester.Errorf("Expected a partial mutation error without rollback but got %v", err)
+	}
+	if names := standIn.names(); !reflect.DeepEqual(names, []string{"createPart", "createAlias"}) {
+		tester.Errorf("Expected no mutation after the failure but got %v", names)
+	}
+}
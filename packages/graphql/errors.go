@@ -0,0 +1,199 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/pkg/errors"
+)
+
+// ErrPartNotFound is returned by the part lookups when no part matches
+var ErrPartNotFound = errors.New("part not found")
+
+// ErrArchiveNotFound is returned by the archive lookups when no archive matches
+var ErrArchiveNotFound = errors.New("archive not found")
+
+// ErrorDetail is a single entry of the errors of a graphql response
+type ErrorDetail struct {
+	Message   string        `json:"message"`
+	Path      []interface{} `json:"path,omitempty"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLError is returned when the catalog answers a request with graphql
+// errors, e.g. for an invalid query or a failed mutation
+type GraphQLError struct {
+	Errors []ErrorDetail
+}
+
+// Error implements error.
+func (e *GraphQLError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, detail := range e.Errors {
+		messages[i] = detail.Message
+		if len(detail.Path) > 0 {
+			path := make([]string, len(detail.Path))
+			for j, element := range detail.Path {
+				path[j] = fmt.Sprint(element)
+			}
+			messages[i] += " (path: " + strings.Join(path, ".") + ")"
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RequestError is returned when the catalog could not be reached or
+// answered with an http error status instead of a graphql response
+type RequestError struct {
+	// http status of the response, 0 if there was no response
+	StatusCode int
+	// error of the transport or the body of the response
+	Err error
+}
+
+// Error implements error.
+func (e *RequestError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("catalog responded with status %d %s: %v", e.StatusCode, http.StatusText(e.StatusCode), e.Err)
+	}
+	return fmt.Sprintf("error contacting catalog: %v", e.Err)
+}
+
+// Unwrap gives the error of the transport.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// the graphql client reports every failure as a message only, so the
+// outcome of a request is recorded by the http client for the context
+// the request was made with and used to give a typed error instead
+type responseRecord struct {
+	mutex      sync.Mutex
+	err        error
+	statusCode int
+	errors     []ErrorDetail
+}
+
+type recordKey struct{}
+
+// recordingDoer is a graphql.Doer recording the outcome of requests
+// made with a context carrying a response record
+type recordingDoer struct {
+	doer graphql.Doer
+}
+
+// Do implements graphql.Doer.
+func (doer *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := doer.doer.Do(req)
+	record, ok := req.Context().Value(recordKey{}).(*responseRecord)
+	if !ok {
+		return resp, err
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err != nil {
+		record.err = err
+		return resp, err
+	}
+	record.statusCode = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	// keep a copy of the body for the graphql client
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, nil
+	}
+	var out struct {
+		Errors []ErrorDetail `json:"errors"`
+	}
+	if json.Unmarshal(body, &out) == nil {
+		record.errors = out.Errors
+	}
+	return resp, nil
+}
+
+// withRecord() gives a context whose requests are recorded
+func withRecord(ctx context.Context) (context.Context, *responseRecord) {
+	record := new(responseRecord)
+	return context.WithValue(ctx, recordKey{}, record), record
+}
+
+// typed() gives the typed error for a failed request, errors not
+// caused by the request or the response are returned as is
+func (record *responseRecord) typed(err error) error {
+	if err == nil {
+		return nil
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	switch {
+	case record.err != nil:
+		return &RequestError{Err: record.err}
+	case record.statusCode != 0 && record.statusCode != http.StatusOK:
+		return &RequestError{StatusCode: record.statusCode, Err: err}
+	case len(record.errors) > 0:
+		return &GraphQLError{Errors: record.errors}
+	}
+	return err
+}
+
+// runQuery() runs the query and gives a typed error if it fails
+func runQuery(ctx context.Context, client *graphql.Client, query interface{}, variables map[string]interface{}) error {
+	ctx, record := withRecord(ctx)
+	return record.typed(client.Query(ctx, query, variables))
+}
+
+// runMutation() runs the mutation and gives a typed error if it fails
+func runMutation(ctx context.Context, client *graphql.Client, mutation interface{}, variables map[string]interface{}) error {
+	ctx, record := withRecord(ctx)
+	return record.typed(client.Mutate(ctx, mutation, variables))
+}
+
+// runRaw() runs the query given as text and gives the raw response data
+// and a typed error if it fails
+func runRaw(ctx context.Context, client *graphql.Client, query string, variables map[string]interface{}) ([]byte, error) {
+	ctx, record := withRecord(ctx)
+	data, err := client.ExecRaw(ctx, query, variables)
+	return data, record.typed(err)
+}
+
+// uploadError() gives the typed error for the errors of an upload response
+func uploadError(responseErrors []interface{}) error {
+	if len(responseErrors) == 0 {
+		return nil
+	}
+	graphQLError := new(GraphQLError)
+	for _, e := range responseErrors {
+		var detail ErrorDetail
+		if data, err := json.Marshal(e); err != nil || json.Unmarshal(data, &detail) != nil {
+			detail.Message = fmt.Sprint(e)
+		}
+		graphQLError.Errors = append(graphQLError.Errors, detail)
+	}
+	return graphQLError
+}
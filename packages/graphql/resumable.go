@@ -0,0 +1,276 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	graphqlUpload "bitbucket.wrs.com/scm/weststar/graphql-upload-go.git"
+	"github.com/pkg/errors"
+)
+
+// version of the tus protocol used for resumable uploads
+const tusVersion = "1.0.0"
+
+// chunk size of resumable uploads if none is given
+const DefaultChunkSize = 8 << 20
+
+// UploadOptions control how an archive is uploaded
+type UploadOptions struct {
+	// called with the number of bytes of the file sent so far, may be nil
+	Progress func(sent int64)
+	// size of the chunks of resumable uploads
+	ChunkSize int64
+	// number of attempts to send a chunk of a resumable upload, the upload
+	// is resumed from the offset the server has after every failed attempt
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// progressReader is an io.Reader reporting the number
+// of bytes read to the progress function
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	progress func(sent int64)
+}
+
+// Read implements io.Reader.
+func (reader *progressReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.sent += int64(n)
+	if reader.progress != nil && n > 0 {
+		reader.progress(reader.sent)
+	}
+	return n, err
+}
+
+// resumableServer is the key of the servers probed for resumable uploads
+type resumableServer struct {
+	httpClient *http.Client
+	uri        string
+}
+
+// results of probing the servers for resumable uploads, so that the files of
+// a recursive upload do not send an OPTIONS request each
+var resumableServers sync.Map
+
+// resumable() checks if the server advertises resumable uploads by answering
+// an OPTIONS request with the tus version and the creation extension. The
+// answer is cached per client and server, failed requests are not.
+func resumable(ctx context.Context, httpClient *http.Client, uri string) bool {
+	key := resumableServer{httpClient: httpClient, uri: uri}
+	if supported, ok := resumableServers.Load(key); ok {
+		return supported.(bool)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, uri, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	supported := false
+	if resp.StatusCode < 300 && resp.Header.Get("Tus-Resumable") != "" {
+		for _, extension := range strings.Split(resp.Header.Get("Tus-Extension"), ",") {
+			if strings.TrimSpace(extension) == "creation" {
+				supported = true
+			}
+		}
+	}
+	resumableServers.Store(key, supported)
+	return supported
+}
+
+// uploadResumable() uploads the file in chunks with the tus protocol. A failed
+// chunk is sent again from the offset the server reports, so a dropped connection
+// only loses the chunk in flight. The request completing the upload may be answered
+// by the server with the graphql response of the upload, the response is nil if
+// it is not, as by standard tus servers, or if the file is empty.
+func uploadResumable(ctx context.Context, httpClient *http.Client, uri string, path string, options UploadOptions) (*graphqlUpload.Response, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	maxAttempts := options.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	location, err := createUpload(ctx, httpClient, uri, filepath.Base(path), info.Size())
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("created resumable upload", slog.String("Location", location), slog.Int64("Size", info.Size()))
+	// creating an upload of an empty file completes it
+	if info.Size() == 0 {
+		return nil, nil
+	}
+
+	buffer := make([]byte, chunkSize)
+	var offset int64
+	failures := 0
+	backoff := options.InitialBackoff
+	for {
+		n, err := file.ReadAt(buffer, offset)
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrapf(err, "error reading %s", path)
+		}
+		newOffset, response, err := sendChunk(ctx, httpClient, location, offset, buffer[:n], options.Progress)
+		if err == nil {
+			failures = 0
+			backoff = options.InitialBackoff
+			offset = newOffset
+			if offset >= info.Size() {
+				return response, nil
+			}
+			continue
+		}
+		var requestErr *RequestError
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// only failures of the connection and the server are resumed
+		if errors.As(err, &requestErr) && requestErr.StatusCode != 0 && requestErr.StatusCode < 500 && requestErr.StatusCode != http.StatusConflict {
+			return nil, err
+		}
+		failures++
+		if failures >= maxAttempts {
+			return nil, errors.Wrapf(err, "upload failed at offset %d after %d attempts", offset, failures)
+		}
+		slog.Warn("resuming upload", slog.Int64("Offset", offset), slog.Int("Attempt", failures+1), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; options.MaxBackoff > 0 && backoff > options.MaxBackoff {
+			backoff = options.MaxBackoff
+		}
+		// continue from the offset the server has
+		if serverOffset, err := uploadOffset(ctx, httpClient, location); err == nil {
+			offset = serverOffset
+		}
+	}
+}
+
+// createUpload() creates a resumable upload of the given size and gives its location
+func createUpload(ctx context.Context, httpClient *http.Client, uri string, name string, size int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte(name)))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", &RequestError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", &RequestError{StatusCode: resp.StatusCode, Err: errors.New("error creating resumable upload")}
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.String() == "" {
+		return "", &RequestError{StatusCode: resp.StatusCode, Err: errors.New("resumable upload created without a location")}
+	}
+	base, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(location).String(), nil
+}
+
+// sendChunk() sends the chunk of the upload at the offset and gives the new offset,
+// and the response of the server if it answered the chunk with a graphql response
+func sendChunk(ctx context.Context, httpClient *http.Client, location string, offset int64, chunk []byte, progress func(int64)) (int64, *graphqlUpload.Response, error) {
+	body := &progressReader{reader: bytes.NewReader(chunk)}
+	if progress != nil {
+		body.progress = func(sent int64) { progress(offset + sent) }
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, location, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.ContentLength = int64(len(chunk))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, &RequestError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, nil, &RequestError{StatusCode: resp.StatusCode, Err: errors.New("error sending upload chunk")}
+	}
+	newOffset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, nil, &RequestError{StatusCode: resp.StatusCode, Err: errors.New("upload chunk accepted without an offset")}
+	}
+	// the request completing the upload may be answered with the graphql response
+	if resp.StatusCode != http.StatusOK {
+		return newOffset, nil, nil
+	}
+	response := new(graphqlUpload.Response)
+	if err = json.NewDecoder(resp.Body).Decode(response); err == io.EOF {
+		return newOffset, nil, nil
+	} else if err != nil {
+		return 0, nil, &RequestError{StatusCode: resp.StatusCode, Err: errors.Wrapf(err, "error decoding upload response")}
+	}
+	return newOffset, response, nil
+}
+
+// uploadOffset() gives the number of bytes of the upload the server has
+func uploadOffset(ctx context.Context, httpClient *http.Client, location string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, location, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return 0, &RequestError{StatusCode: resp.StatusCode, Err: errors.New("error retrieving upload offset")}
+	}
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tusServer is a test server accepting a single resumable upload, which
+// fails the first attempt of a chunk after keeping half of its bytes
+type tusServer struct {
+	mutex    sync.Mutex
+	size     int64
+	received []byte
+	probes   int
+	patches  int
+	// number of the patch request failing
+	failPatch int
+	// completes the upload without a graphql response like standard tus
+	// servers, the archive is then retrieved with a graphql query
+	standard bool
+}
+
+// ServeHTTP implements http.Handler.
+func (server *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	w.Header().Set("Tus-Resumable", tusVersion)
+	switch {
+	case r.Method == http.MethodOptions:
+		server.probes++
+		w.Header().Set("Tus-Extension", "creation")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.Header.Get("Upload-Length") == "":
+		w.Write([]byte(`{"data":{"archive":{"name":"test.tar.gz","sha256":"e3b0","part_id":"0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"}}}`))
+	case r.Method == http.MethodPost:
+		server.size, _ = strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		w.Header().Set("Location", "/files/1")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead && r.URL.Path == "/files/1":
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(server.received)))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPatch && r.URL.Path == "/files/1":
+		server.patches++
+		if offset, _ := strconv.Atoi(r.Header.Get("Upload-Offset")); offset != len(server.received) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		if server.patches == server.failPatch {
+			server.received = append(server.received, chunk[:len(chunk)/2]...)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		server.received = append(server.received, chunk...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(server.received)))
+		if int64(len(server.received)) < server.size || server.standard {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"data":{"uploadArchive":{"name":"test.tar.gz","part_id":"0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"}}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestResumableUpload checks if an upload interrupted by a failed chunk
+// is resumed from the offset of the server
+func TestResumableUpload(tester *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 160)
+	path := filepath.Join(tester.TempDir(), "test.tar.gz")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		tester.Fatal("failed to write file", err)
+	}
+	handler := &tusServer{failPatch: 2}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var sent int64
+	options := UploadOptions{
+		Progress:       func(n int64) { sent = n },
+		ChunkSize:      1024,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+	archive, err := UploadFile(context.Background(), server.Client(), server.URL, path, "", options)
+	if err != nil {
+		tester.Fatal("failed to upload file", err)
+	}
+	if !bytes.Equal(handler.received, content) {
+		tester.Errorf("Expected the server to receive %d bytes of the file but got %d", len(content), len(handler.received))
+	}
+	// the upload is resumed from the middle of the second chunk, so the
+	// third request sends the rest of the file instead of a fourth one
+	if handler.patches != 3 {
+		tester.Errorf("Expected 3 chunks to be sent but got %d", handler.patches)
+	}
+	if sent != int64(len(content)) {
+		tester.Errorf("Expected the progress to reach %d bytes but got %d", len(content), sent)
+	}
+	if archive.Name != "test.tar.gz" || archive.PartID.String() != "0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f" {
+		tester.Errorf("Expected the archive of the upload response but got %+v", archive)
+	}
+}
+
+// TestResumableUploadStandard checks if the archive of an upload completed
+// without a graphql response, and of an empty file, is retrieved by its sha256
+// and if the server is probed for resumable uploads only once
+func TestResumableUploadStandard(tester *testing.T) {
+	dir := tester.TempDir()
+	handler := &tusServer{standard: true}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	options := UploadOptions{ChunkSize: 1024, MaxAttempts: 1}
+	for _, size := range []int{2048, 0} {
+		handler.received, handler.patches = nil, 0
+		path := filepath.Join(dir, "test-"+strconv.Itoa(size)+".tar.gz")
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			tester.Fatal("failed to write file", err)
+		}
+		archive, err := UploadFile(context.Background(), server.Client(), server.URL, path, "", options)
+		if err != nil {
+			tester.Fatalf("failed to upload file of %d bytes: %v", size, err)
+		}
+		if len(handler.received) != size || handler.patches != size/1024 {
+			tester.Errorf("Expected %d bytes in %d chunks but got %d bytes in %d", size, size/1024, len(handler.received), handler.patches)
+		}
+		if archive.Sha256 != "e3b0" || archive.PartID.String() != "0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f" {
+			tester.Errorf("Expected the archive retrieved by its sha256 but got %+v", archive)
+		}
+	}
+	if handler.probes != 1 {
+		tester.Errorf("Expected the server to be probed once but got %d", handler.probes)
+	}
+}
+
+// TestResumableUploadFails checks if an upload gives up after the configured attempts
+func TestResumableUploadFails(tester *testing.T) {
+	path := filepath.Join(tester.TempDir(), "test.tar.gz")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 2048), 0644); err != nil {
+		tester.Fatal("failed to write file", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		switch r.Method {
+		case http.MethodOptions:
+			w.Header().Set("Tus-Extension", "creation,termination")
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPost:
+			w.Header().Set("Location", "/files/1")
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodHead:
+			w.Header().Set("Upload-Offset", "0")
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	options := UploadOptions{ChunkSize: 1024, MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	_, err := UploadFile(context.Background(), server.Client(), server.URL, path, "", options)
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusServiceUnavailable {
+		tester.Errorf("Expected the upload to fail with status 503 but got %v", err)
+	}
+}
+
+// TestNotResumable checks if servers without tus support are not used for resumable uploads
+func TestNotResumable(tester *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+	if resumable(context.Background(), server.Client(), server.URL) {
+		tester.Error("Expected a server without tus support not to be resumable")
+	}
+}
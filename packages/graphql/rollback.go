@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/pkg/errors"
+)
+
+// time given to the compensating mutations if the context of the failed
+// operation is already cancelled, e.g. by Ctrl-C or the command timeout
+const rollbackTimeout = time.Minute
+
+// journal records the mutations applied by a multi step operation
+// together with the mutations compensating them
+type journal struct {
+	entries []journalEntry
+}
+
+type journalEntry struct {
+	// description of the applied mutation
+	applied string
+	// description and function of the compensating mutation, entries
+	// without one are compensated by an earlier entry
+	compensation string
+	compensate   func(ctx context.Context, client *graphql.Client) error
+}
+
+// record() adds an applied mutation to the journal
+func (j *journal) record(applied string, compensation string, compensate func(ctx context.Context, client *graphql.Client) error) {
+	j.entries = append(j.entries, journalEntry{applied: applied, compensation: compensation, compensate: compensate})
+}
+
+// applied() lists the descriptions of the applied mutations in order
+func (j *journal) applied() []string {
+	var applied []string
+	for _, entry := range j.entries {
+		applied = append(applied, entry.applied)
+	}
+	return applied
+}
+
+// rollback() runs the compensating mutations in reverse order and gives
+// the descriptions of the successful ones and the first error encountered
+func (j *journal) rollback(ctx context.Context, client *graphql.Client) ([]string, error) {
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+	}
+	var rolledBack []string
+	var rollbackErr error
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entry := j.entries[i]
+		if entry.compensate == nil {
+			continue
+		}
+		slog.Debug("rolling back mutation", slog.String("Applied", entry.applied), slog.String("Compensation", entry.compensation))
+		if err := entry.compensate(ctx, client); err != nil {
+			slog.Error("error rolling back mutation", slog.String("Applied", entry.applied), slog.Any("Error", err))
+			if rollbackErr == nil {
+				rollbackErr = errors.Wrapf(err, "error rolling back %s", entry.applied)
+			}
+			continue
+		}
+		rolledBack = append(rolledBack, entry.compensation)
+	}
+	return rolledBack, rollbackErr
+}
+
+// PartialMutationError is returned when a multi step operation failed
+// after some of its mutations were applied to the catalog
+type PartialMutationError struct {
+	Err error
+	// mutations applied before the failure, in order
+	Applied []string
+	// compensating mutations which were run successfully, in order
+	RolledBack []string
+	// error of the rollback, if it could not be completed
+	RollbackErr error
+}
+
+// Error implements error.
+func (e *PartialMutationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap gives the error which caused the operation to fail.
+func (e *PartialMutationError) Unwrap() error {
+	return e.Err
+}
+
+// fail() wraps the error of a failed operation, rolling back the applied
+// mutations if requested. Errors before any mutation was applied are
+// returned as is.
+func (j *journal) fail(ctx context.Context, client *graphql.Client, err error, rollback bool) error {
+	if len(j.entries) == 0 {
+		return err
+	}
+	partialErr := &PartialMutationError{Err: err, Applied: j.applied()}
+	if rollback {
+		partialErr.RolledBack, partialErr.RollbackErr = j.rollback(ctx, client)
+	}
+	return partialErr
+}
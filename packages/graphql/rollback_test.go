@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/pkg/errors"
+)
+
+// TestJournalRollback checks that the compensating mutations run in reverse
+// order, skipping entries without one and continuing after a failed one
+func TestJournalRollback(tester *testing.T) {
+	standIn := newCatalogStandIn(tester, "deletePart")
+	client := GetNewClient(standIn.server.URL, standIn.server.Client())
+	var compensated []string
+	compensate := func(name string) func(ctx context.Context, client *graphql.Client) error {
+		return func(ctx context.Context, client *graphql.Client) error {
+			compensated = append(compensated, name)
+			return nil
+		}
+	}
+	var applied journal
+	applied.record("createPart a", "deletePart a", compensate("a"))
+	applied.record("createAlias a", "", nil)
+	applied.record("createPart b", "deletePart b", func(ctx context.Context, client *graphql.Client) error {
+		// fails with the error of the stand-in
+		return DeletePart(ctx, client, standInPartID, false, true)
+	})
+	applied.record("createPart c", "deletePart c", compensate("c"))
+
+	cause := errors.New("createAlias failed")
+	err := applied.fail(context.Background(), client, cause, true)
+	var partialErr *PartialMutationError
+	if !errors.As(err, &partialErr) || !errors.Is(err, cause) {
+		tester.Fatalf("Expected a partial mutation error of the cause but got %v", err)
+	}
+	if expected := []string{"c", "a"}; !reflect.DeepEqual(compensated, expected) {
+		tester.Errorf("Expected the compensations %v but got %v", expected, compensated)
+	}
+	if expected := []string{"deletePart c", "deletePart a"}; !reflect.DeepEqual(partialErr.RolledBack, expected) {
+		tester.Errorf("Expected the rollback %v but got %v", expected, partialErr.RolledBack)
+	}
+	if partialErr.RollbackErr == nil {
+		tester.Error("Expected the failed compensation to be reported")
+	}
+
+	// errors before any mutation was applied are returned as is
+	var empty journal
+	if err = empty.fail(context.Background(), client, cause, true); err != cause {
+		tester.Errorf("Expected the error as is but got %v", err)
+	}
+}
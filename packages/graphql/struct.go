@@ -26,32 +26,32 @@ type JSON string
 type Upload os.File
 
 type Archive struct {
-	Sha256     string    `graphql:"sha256"`
-	Size       int64     `graphql:"size"`
-	PartID     uuid.UUID `graphql:"part_id"`
-	Part       Part
-	Md5        string `graphql:"md5"`
-	Sha1       string `graphql:"sha1"`
-	Name       string `graphql:"name"`
-	InsertDate string `graphql:"insert_date"`
+	Sha256     string    `graphql:"sha256" json:"sha256"`
+	Size       int64     `graphql:"size" json:"size"`
+	PartID     uuid.UUID `graphql:"part_id" json:"part_id"`
+	Part       Part      `json:"part"`
+	Md5        string    `graphql:"md5" json:"md5"`
+	Sha1       string    `graphql:"sha1" json:"sha1"`
+	Name       string    `graphql:"name" json:"name"`
+	InsertDate string    `graphql:"insert_date" json:"insert_date"`
 }
 
 type Part struct {
-	ID                   uuid.UUID `graphql:"id" yaml:"id"`
-	PartType             string    `graphql:"type" yaml:"type"`
-	ContentType          string    `graphql:"content_type" yaml:"content_type"`
-	Version              string    `graphql:"version" yaml:"version"`
-	Name                 string    `graphql:"name" yaml:"name"`
-	Label                string    `graphql:"label" yaml:"label"`
-	FamilyName           string    `graphql:"family_name" yaml:"family_name"`
-	FileVerificationCode string    `graphql:"file_verification_code" yaml:"file_verification_code"`
-	Size                 int64     `graphql:"size" yaml:"size"`
-	License              string    `graphql:"license" yaml:"license"`
-	LicenseRationale     string    `graphql:"license_rationale" yaml:"license_rationale"`
-	Description          string    `graphql:"description" yaml:"description"`
-	HomePage             string    `graphql:"home_page" yaml:"home_page"`
-	Comprised            uuid.UUID `graphql:"comprised" yaml:"comprised"`
-	Aliases              []string  `graphql:"aliases" yaml:"aliases"`
+	ID                   uuid.UUID `graphql:"id" json:"id" yaml:"id"`
+	PartType             string    `graphql:"type" json:"type" yaml:"type"`
+	ContentType          string    `graphql:"content_type" json:"content_type" yaml:"content_type"`
+	Version              string    `graphql:"version" json:"version" yaml:"version"`
+	Name                 string    `graphql:"name" json:"name" yaml:"name"`
+	Label                string    `graphql:"label" json:"label" yaml:"label"`
+	FamilyName           string    `graphql:"family_name" json:"family_name" yaml:"family_name"`
+	FileVerificationCode string    `graphql:"file_verification_code" json:"file_verification_code" yaml:"file_verification_code"`
+	Size                 int64     `graphql:"size" json:"size" yaml:"size"`
+	License              string    `graphql:"license" json:"license" yaml:"license"`
+	LicenseRationale     string    `graphql:"license_rationale" json:"license_rationale" yaml:"license_rationale"`
+	Description          string    `graphql:"description" json:"description" yaml:"description"`
+	HomePage             string    `graphql:"home_page" json:"home_page" yaml:"home_page"`
+	Comprised            uuid.UUID `graphql:"comprised" json:"comprised" yaml:"comprised"`
+	Aliases              []string  `graphql:"aliases" json:"aliases" yaml:"aliases"`
 }
 
 type PartInput struct {
@@ -0,0 +1,46 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"net/http"
+	"wrs/catalog/ccli/packages/config"
+)
+
+// header carrying the api key if none is configured
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// AuthTransport is a http.RoundTripper adding the configured
+// credentials to every request sent through the base transport
+type AuthTransport struct {
+	Base http.RoundTripper
+	Auth config.AuthConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (transport *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the request given to a round tripper must not be modified
+	req = req.Clone(req.Context())
+	switch {
+	case transport.Auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+transport.Auth.Token)
+	case transport.Auth.Username != "":
+		req.SetBasicAuth(transport.Auth.Username, transport.Auth.Password)
+	case transport.Auth.APIKey != "":
+		header := transport.Auth.APIKeyHeader
+		if header == "" {
+			header = DefaultAPIKeyHeader
+		}
+		req.Header.Set(header, transport.Auth.APIKey)
+	}
+	return transport.Base.RoundTrip(req)
+}
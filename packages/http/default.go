@@ -10,22 +10,61 @@
 // under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
 // OR CONDITIONS OF ANY KIND, either express or implied.
 
-// Provides default http client with tls verification ignored for self signed certificates and testing
+// Provides the default http client used for all requests to the catalog, configured with the user's tls settings and credentials
 package http
 
 import (
-	"crypto/tls"
 	"net/http"
+	"wrs/catalog/ccli/packages/config"
 )
 
 var DefaultClient *http.Client
 
 func init() {
-	// creating a default http client
+	// creating a default http client which verifies server certificates
 	DefaultClient = &http.Client{
-		Transport: &http.Transport{
-			// ignore verification for self signed certificates
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
 	}
 }
+
+// Configure() applies the tls settings, credentials and retry policy of the configuration to the default client
+func Configure(configFile *config.ConfigData) error {
+	transport, err := NewTransport(configFile.TLS)
+	if err != nil {
+		return err
+	}
+	// tokens of an openid connect provider are obtained and renewed on demand
+	if configFile.Auth.UsesOIDC() {
+		DefaultClient.Transport = WithRetry(&TokenTransport{
+			Base: transport,
+			Source: &OIDCTokenSource{
+				Client:     &http.Client{Transport: transport},
+				Config:     configFile.Auth.OIDC,
+				ServerAddr: configFile.ServerAddr,
+			},
+		}, configFile.Retry)
+		return nil
+	}
+	DefaultClient.Transport = WithRetry(WithAuth(transport, configFile.Auth), configFile.Retry)
+	return nil
+}
+
+// NewTransport() creates a transport using the given tls settings
+func NewTransport(tlsSettings config.TLSConfig) (*http.Transport, error) {
+	tlsConfig, err := NewTLSConfig(tlsSettings)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// WithAuth() wraps the transport for sending the given credentials, the
+// transport is returned as is if no credentials are set
+func WithAuth(transport http.RoundTripper, auth config.AuthConfig) http.RoundTripper {
+	if !auth.IsSet() {
+		return transport
+	}
+	return &AuthTransport{Base: transport, Auth: auth}
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"wrs/catalog/ccli/packages/config"
+
+	"github.com/pkg/errors"
+)
+
+// grant types used with the openid connect provider
+const (
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// tokens are renewed this long before they expire
+const expiryDelta = 30 * time.Second
+
+// struct for storing the endpoints of an openid connect provider
+type OIDCProvider struct {
+	Issuer                      string `json:"issuer"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// struct for storing the token endpoint response
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"`
+	Expiry       time.Time `json:"-"`
+}
+
+// struct for storing the device authorization endpoint response
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// struct for storing an oauth2 error response
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error implements error.
+func (oauthError *OAuthError) Error() string {
+	if oauthError.Description != "" {
+		return fmt.Sprintf("oauth2 error %s: %s", oauthError.Code, oauthError.Description)
+	}
+	return "oauth2 error " + oauthError.Code
+}
+
+// DiscoverOIDC() reads the endpoints of the provider from its openid configuration
+func DiscoverOIDC(ctx context.Context, client *http.Client, issuer string) (*OIDCProvider, error) {
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating discovery request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error contacting openid connect provider")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error reading openid configuration from %s, status code: %d", discoveryURL, resp.StatusCode)
+	}
+	provider := new(OIDCProvider)
+	if err = json.NewDecoder(resp.Body).Decode(provider); err != nil {
+		return nil, errors.Wrapf(err, "error decoding openid configuration")
+	}
+	if provider.TokenEndpoint == "" {
+		return nil, errors.New("error reading openid configuration, no token endpoint")
+	}
+	return provider, nil
+}
+
+// DeviceAuthorization() starts the device authorization grant, the user
+// has to visit the verification uri and enter the user code
+func (provider *OIDCProvider) DeviceAuthorization(ctx context.Context, client *http.Client, oidcConfig config.OIDCConfig) (*DeviceCode, error) {
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return nil, errors.New("openid connect provider does not support the device authorization grant")
+	}
+	form := url.Values{"client_id": {oidcConfig.ClientID}}
+	if len(oidcConfig.Scopes) > 0 {
+		form.Set("scope", strings.Join(oidcConfig.Scopes, " "))
+	}
+	body, err := provider.post(ctx, client, provider.DeviceAuthorizationEndpoint, oidcConfig, form)
+	if err != nil {
+		return nil, err
+	}
+	deviceCode := new(DeviceCode)
+	if err = json.Unmarshal(body, deviceCode); err != nil {
+		return nil, errors.Wrapf(err, "error decoding device authorization response")
+	}
+	if deviceCode.Interval <= 0 {
+		deviceCode.Interval = 5
+	}
+	return deviceCode, nil
+}
+
+// PollDeviceToken() polls the token endpoint until the user has approved or
+// denied the device authorization, or the device code has expired
+func (provider *OIDCProvider) PollDeviceToken(ctx context.Context, client *http.Client, oidcConfig config.OIDCConfig, deviceCode *DeviceCode) (*Token, error) {
+	interval := time.Duration(deviceCode.Interval) * time.Second
+	form := url.Values{
+		"grant_type":  {GrantTypeDeviceCode},
+		"device_code": {deviceCode.DeviceCode},
+		"client_id":   {oidcConfig.ClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		token, err := provider.requestToken(ctx, client, oidcConfig, form)
+		var oauthError *OAuthError
+		if errors.As(err, &oauthError) {
+			switch oauthError.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				// the interval has to be increased by 5 seconds as per RFC 8628
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		return token, err
+	}
+}
+
+// ClientCredentialsToken() obtains a token using the client credentials grant
+func (provider *OIDCProvider) ClientCredentialsToken(ctx context.Context, client *http.Client, oidcConfig config.OIDCConfig) (*Token, error) {
+	if oidcConfig.ClientSecret == "" {
+		return nil, errors.New("the client credentials grant requires a client secret")
+	}
+	form := url.Values{"grant_type": {GrantTypeClientCredentials}}
+	if len(oidcConfig.Scopes) > 0 {
+		form.Set("scope", strings.Join(oidcConfig.Scopes, " "))
+	}
+	return provider.requestToken(ctx, client, oidcConfig, form)
+}
+
+// RefreshToken() obtains a new token using a refresh token
+func (provider *OIDCProvider) RefreshToken(ctx context.Context, client *http.Client, oidcConfig config.OIDCConfig, refreshToken string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {GrantTypeRefreshToken},
+		"refresh_token": {refreshToken},
+	}
+	token, err := provider.requestToken(ctx, client, oidcConfig, form)
+	if err != nil {
+		return nil, err
+	}
+	// the provider may keep the refresh token unchanged
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// requestToken() posts the form to the token endpoint and decodes the token
+func (provider *OIDCProvider) requestToken(ctx context.Context, client *http.Client, oidcConfig config.OIDCConfig, form url.Values) (*Token, error) {
+	body, err := provider.post(ctx, client, provider.TokenEndpoint, oidcConfig, form)
+	if err != nil {
+		return nil, err
+	}
+	token := new(Token)
+	if err = json.Unmarshal(body, token); err != nil {
+		return nil, errors.Wrapf(err, "error decoding token response")
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("error reading token response, no access token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// post() sends a form to an endpoint of the provider, authenticating with
+// the client secret if one is configured, and gives the response body
+func (provider *OIDCProvider) post(ctx context.Context, client *http.Client, endpoint string, oidcConfig config.OIDCConfig, form url.Values) ([]byte, error) {
+	// public clients identify themselves in the form
+	if oidcConfig.ClientSecret == "" && form.Get("client_id") == "" {
+		form.Set("client_id", oidcConfig.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oidcConfig.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(oidcConfig.ClientID), url.QueryEscape(oidcConfig.ClientSecret))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error contacting openid connect provider")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading response from openid connect provider")
+	}
+	if resp.StatusCode != http.StatusOK {
+		oauthError := new(OAuthError)
+		if json.Unmarshal(body, oauthError) == nil && oauthError.Code != "" {
+			return nil, oauthError
+		}
+		return nil, errors.Errorf("error from openid connect provider, status code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// TokenSource gives the bearer token to be sent with a request
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenTransport is a http.RoundTripper adding the bearer token
+// of the token source to every request sent through the base transport
+type TokenTransport struct {
+	Base   http.RoundTripper
+	Source TokenSource
+}
+
+// RoundTrip implements http.RoundTripper.
+func (transport *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := transport.Source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	// the request given to a round tripper must not be modified
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return transport.Base.RoundTrip(req)
+}
+
+// OIDCTokenSource gives the openid connect token cached in the credentials
+// file for the server, renewing it with the refresh token or the client
+// credentials grant once it has expired
+type OIDCTokenSource struct {
+	// client used to contact the provider
+	Client     *http.Client
+	Config     config.OIDCConfig
+	ServerAddr string
+	mutex      sync.Mutex
+	provider   *OIDCProvider
+	cached     *config.ServerCredentials
+}
+
+// Token implements TokenSource.
+func (source *OIDCTokenSource) Token(ctx context.Context) (string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	// read the cached token on first use
+	if source.cached == nil {
+		credentials, err := config.ReadCredentials()
+		if err != nil {
+			return "", err
+		}
+		cached := credentials.Servers[source.ServerAddr]
+		source.cached = &cached
+	}
+	if source.cached.Token != "" && (source.cached.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(source.cached.Expiry)) {
+		return source.cached.Token, nil
+	}
+	// renew the token
+	if source.provider == nil {
+		provider, err := DiscoverOIDC(ctx, source.Client, source.Config.Issuer)
+		if err != nil {
+			return "", err
+		}
+		source.provider = provider
+	}
+	var token *Token
+	var err error
+	if source.cached.RefreshToken != "" {
+		slog.Debug("refreshing openid connect token", slog.String("Issuer", source.Config.Issuer))
+		token, err = source.provider.RefreshToken(ctx, source.Client, source.Config, source.cached.RefreshToken)
+		if err != nil {
+			slog.Debug("error refreshing openid connect token", slog.Any("error", err))
+		}
+	}
+	if token == nil && source.Config.ClientSecret != "" {
+		slog.Debug("requesting openid connect token using client credentials", slog.String("Issuer", source.Config.Issuer))
+		token, err = source.provider.ClientCredentialsToken(ctx, source.Client, source.Config)
+	}
+	if token == nil {
+		if err != nil {
+			return "", errors.Wrapf(err, "openid connect token expired, run ccli login --oidc")
+		}
+		return "", errors.New("no valid openid connect token, run ccli login --oidc")
+	}
+	// cache the new token
+	source.cached = &config.ServerCredentials{Token: token.AccessToken, RefreshToken: token.RefreshToken, Expiry: token.Expiry}
+	if err = config.StoreServerCredentials(source.ServerAddr, *source.cached); err != nil {
+		slog.Warn("error caching openid connect token", slog.Any("error", err))
+	}
+	return source.cached.Token, nil
+}
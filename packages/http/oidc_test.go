@@ -0,0 +1,193 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"wrs/catalog/ccli/packages/config"
+)
+
+// oidcStandIn is a minimal openid connect provider supporting the device
+// authorization, client credentials and refresh token grants
+type oidcStandIn struct {
+	mutex sync.Mutex
+	// number of polls answered with authorization_pending
+	pending int
+	issued  int
+	server  *httptest.Server
+}
+
+func newOIDCStandIn(tester *testing.T) *oidcStandIn {
+	standIn := new(oidcStandIn)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        standIn.server.URL,
+			"token_endpoint":                standIn.server.URL + "/token",
+			"device_authorization_endpoint": standIn.server.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "ccli" {
+			tester.Errorf("Expected client_id ccli but got %s", r.FormValue("client_id"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": standIn.server.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		standIn.mutex.Lock()
+		defer standIn.mutex.Unlock()
+		switch r.FormValue("grant_type") {
+		case GrantTypeDeviceCode:
+			if standIn.pending > 0 {
+				standIn.pending--
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+		case GrantTypeClientCredentials:
+			if id, secret, ok := r.BasicAuth(); !ok || id != "ccli" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+				return
+			}
+		case GrantTypeRefreshToken:
+			if r.FormValue("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		}
+		standIn.issued++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-" + r.FormValue("grant_type"),
+			"token_type":    "Bearer",
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	})
+	standIn.server = httptest.NewServer(mux)
+	tester.Cleanup(standIn.server.Close)
+	return standIn
+}
+
+// TestDeviceAuthorization runs the device authorization grant against the
+// stand in provider and checks if pending authorizations are polled again
+func TestDeviceAuthorization(tester *testing.T) {
+	standIn := newOIDCStandIn(tester)
+	standIn.pending = 1
+	oidcConfig := config.OIDCConfig{Issuer: standIn.server.URL, ClientID: "ccli"}
+	ctx := context.Background()
+	provider, err := DiscoverOIDC(ctx, standIn.server.Client(), oidcConfig.Issuer)
+	if err != nil {
+		tester.Fatal("failed to discover provider", err)
+	}
+	deviceCode, err := provider.DeviceAuthorization(ctx, standIn.server.Client(), oidcConfig)
+	if err != nil {
+		tester.Fatal("failed to start device authorization", err)
+	}
+	if deviceCode.UserCode != "ABCD-EFGH" {
+		tester.Errorf("Expected user code ABCD-EFGH but got %s", deviceCode.UserCode)
+	}
+	token, err := provider.PollDeviceToken(ctx, standIn.server.Client(), oidcConfig, deviceCode)
+	if err != nil {
+		tester.Fatal("failed to poll token", err)
+	}
+	expected := "access-" + GrantTypeDeviceCode
+	if token.AccessToken != expected {
+		tester.Errorf("Expected %s but got %s", expected, token.AccessToken)
+	}
+	if standIn.pending != 0 {
+		tester.Errorf("Expected the pending authorization to be polled again")
+	}
+	if token.Expiry.Before(time.Now()) {
+		tester.Errorf("Expected the token expiry to be in the future but got %s", token.Expiry)
+	}
+}
+
+// TestTokenTransport checks if an expired cached token is refreshed and the
+// new token is sent to the catalog and written to the credentials file
+func TestTokenTransport(tester *testing.T) {
+	standIn := newOIDCStandIn(tester)
+	tester.Setenv(config.CredentialsEnv, filepath.Join(tester.TempDir(), "credentials.yml"))
+	var authorization string
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer catalog.Close()
+	// cache an expired token with a refresh token
+	expired := config.ServerCredentials{Token: "expired", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)}
+	if err := config.StoreServerCredentials(catalog.URL, expired); err != nil {
+		tester.Fatal("failed to store credentials", err)
+	}
+	client := &http.Client{Transport: &TokenTransport{
+		Base: http.DefaultTransport,
+		Source: &OIDCTokenSource{
+			Client:     standIn.server.Client(),
+			Config:     config.OIDCConfig{Issuer: standIn.server.URL, ClientID: "ccli"},
+			ServerAddr: catalog.URL,
+		},
+	}}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(catalog.URL)
+		if err != nil {
+			tester.Fatal("failed to contact catalog", err)
+		}
+		resp.Body.Close()
+	}
+	expected := "Bearer access-" + GrantTypeRefreshToken
+	if authorization != expected {
+		tester.Errorf("Expected %s but got %s", expected, authorization)
+	}
+	if standIn.issued != 1 {
+		tester.Errorf("Expected the refreshed token to be reused but %d tokens were issued", standIn.issued)
+	}
+	credentials, err := config.ReadCredentials()
+	if err != nil {
+		tester.Fatal("failed to read credentials", err)
+	}
+	if credentials.Servers[catalog.URL].Token != "access-"+GrantTypeRefreshToken {
+		tester.Errorf("Expected the refreshed token to be cached but got %s", credentials.Servers[catalog.URL].Token)
+	}
+}
+
+// TestClientCredentials checks if the token source falls back to the client
+// credentials grant when there is no cached token
+func TestClientCredentials(tester *testing.T) {
+	standIn := newOIDCStandIn(tester)
+	tester.Setenv(config.CredentialsEnv, filepath.Join(tester.TempDir(), "credentials.yml"))
+	source := &OIDCTokenSource{
+		Client:     standIn.server.Client(),
+		Config:     config.OIDCConfig{Issuer: standIn.server.URL, ClientID: "ccli", ClientSecret: "secret"},
+		ServerAddr: "https://catalog.example.com/api/graphql",
+	}
+	token, err := source.Token(context.Background())
+	if err != nil {
+		tester.Fatal("failed to obtain token", err)
+	}
+	expected := "access-" + GrantTypeClientCredentials
+	if token != expected {
+		tester.Errorf("Expected %s but got %s", expected, token)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"wrs/catalog/ccli/packages/config"
+
+	"github.com/pkg/errors"
+)
+
+// RetryTransport is a http.RoundTripper retrying requests which failed with
+// a transient error. Queries and idempotent requests without a body are retried,
+// mutations, other requests without a body and requests whose body cannot be
+// read again only if the policy allows it.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy config.RetryConfig
+}
+
+// WithRetry() wraps the transport for retrying requests with the given
+// policy, the transport is returned as is if retries are disabled
+func WithRetry(transport http.RoundTripper, policy config.RetryConfig) http.RoundTripper {
+	if policy.MaxAttempts < 2 {
+		return transport
+	}
+	return &RetryTransport{Base: transport, Policy: policy}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (transport *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !transport.canRetry(req) {
+		return transport.Base.RoundTrip(req)
+	}
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			// send a fresh copy of the body with every attempt
+			attemptReq = req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, errors.Wrapf(err, "error retrying request")
+				}
+				attemptReq.Body = body
+			}
+		}
+		resp, err := transport.Base.RoundTrip(attemptReq)
+		reason := retryReason(req, resp, err)
+		if reason == "" || attempt >= transport.Policy.MaxAttempts {
+			return resp, err
+		}
+		delay := transport.backoff(attempt, resp)
+		if resp != nil {
+			// the connection can only be reused once the body is read
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slog.Warn("retrying request", slog.String("URL", req.URL.String()), slog.String("Reason", reason), slog.Int("Attempt", attempt), slog.Duration("Delay", delay))
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// canRetry() checks if the policy allows to retry the request and if
+// its body can be sent again
+func (transport *RetryTransport) canRetry(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		// requests without a body, such as the creation of a resumable
+		// upload, can still change the server unless their method is idempotent
+		return transport.Policy.Mutations || isIdempotent(req.Method)
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	return transport.Policy.Mutations || !isMutation(req)
+}
+
+// backoff() gives the delay before the given attempt is retried, which is
+// doubled with every attempt and randomized by the jitter. A Retry-After
+// header of the response is honored up to the maximum backoff.
+func (transport *RetryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	delay := transport.Policy.InitialBackoff
+	for i := 1; i < attempt && delay < transport.Policy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if transport.Policy.Jitter > 0 {
+		delay += time.Duration(float64(delay) * transport.Policy.Jitter * (2*rand.Float64() - 1))
+	}
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	if transport.Policy.MaxBackoff > 0 && delay > transport.Policy.MaxBackoff {
+		delay = transport.Policy.MaxBackoff
+	}
+	return delay
+}
+
+// retryReason() describes why the attempt is to be retried, an empty
+// reason means the attempt succeeded or failed permanently
+func retryReason(req *http.Request, resp *http.Response, err error) string {
+	if err != nil {
+		// cancelled requests are never retried
+		if req.Context().Err() != nil {
+			return ""
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+			return err.Error()
+		}
+		return ""
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status
+	}
+	return ""
+}
+
+// isIdempotent() checks if requests of the method can be sent again
+// without changing the server more than the first one
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isMutation() checks if the request is a graphql mutation, requests which
+// are not a json encoded graphql query, such as uploads, count as mutations
+func isMutation(req *http.Request) bool {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return true
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return true
+	}
+	defer body.Close()
+	var request struct {
+		Query string `json:"query"`
+	}
+	if err = json.NewDecoder(body).Decode(&request); err != nil {
+		return true
+	}
+	// skip the whitespace and comments preceding the operation
+	query := strings.TrimSpace(request.Query)
+	for strings.HasPrefix(query, "#") {
+		if i := strings.Index(query, "\n"); i >= 0 {
+			query = strings.TrimSpace(query[i:])
+		} else {
+			query = ""
+		}
+	}
+	return !strings.HasPrefix(query, "query") && !strings.HasPrefix(query, "{")
+}
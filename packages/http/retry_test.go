@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wrs/catalog/ccli/packages/config"
+)
+
+// newFlakyServer() starts a server answering the given number of
+// requests with 502 before it succeeds, and counts the requests
+func newFlakyServer(tester *testing.T, failures int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "__typename") {
+			tester.Errorf("Expected the request body to be sent again but got %q", body)
+		}
+		if requests <= failures {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"data":{"__typename":"Query"}}`))
+	}))
+	tester.Cleanup(server.Close)
+	return server, &requests
+}
+
+// post() sends the graphql document through a client retrying with the given policy
+func post(tester *testing.T, url string, policy config.RetryConfig, document string) *http.Response {
+	client := &http.Client{Transport: WithRetry(http.DefaultTransport, policy)}
+	resp, err := client.Post(url, "application/json", strings.NewReader(`{"query":"`+document+`"}`))
+	if err != nil {
+		tester.Fatal("failed to send request", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// TestRetryQuery checks if queries are retried until they succeed
+func TestRetryQuery(tester *testing.T) {
+	server, requests := newFlakyServer(tester, 2)
+	policy := config.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Jitter: 0.5}
+	resp := post(tester, server.URL, policy, "query{__typename}")
+	if resp.StatusCode != http.StatusOK {
+		tester.Errorf("Expected status 200 but got %d", resp.StatusCode)
+	}
+	if *requests != 3 {
+		tester.Errorf("Expected 3 requests but got %d", *requests)
+	}
+}
+
+// TestRetryMutation checks if mutations are only retried if the policy allows it
+func TestRetryMutation(tester *testing.T) {
+	policy := config.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	server, requests := newFlakyServer(tester, 1)
+	resp := post(tester, server.URL, policy, "mutation{__typename}")
+	if resp.StatusCode != http.StatusBadGateway || *requests != 1 {
+		tester.Errorf("Expected a single failed request but got %d requests with status %d", *requests, resp.StatusCode)
+	}
+	policy.Mutations = true
+	server, requests = newFlakyServer(tester, 1)
+	resp = post(tester, server.URL, policy, "mutation{__typename}")
+	if resp.StatusCode != http.StatusOK || *requests != 2 {
+		tester.Errorf("Expected the mutation to be retried but got %d requests with status %d", *requests, resp.StatusCode)
+	}
+}
+
+// TestRetryWithoutBody checks if requests without a body are only retried
+// if their method is idempotent, e.g. not the creation of a resumable upload
+func TestRetryWithoutBody(tester *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method]++
+		if requests[r.Method] == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	policy := config.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	client := &http.Client{Transport: WithRetry(http.DefaultTransport, policy)}
+	for _, method := range []string{http.MethodPost, http.MethodHead} {
+		req, err := http.NewRequest(method, server.URL, nil)
+		if err != nil {
+			tester.Fatal("failed to create request", err)
+		}
+		req.Header.Set("Upload-Length", "2048")
+		resp, err := client.Do(req)
+		if err != nil {
+			tester.Fatal("failed to send request", err)
+		}
+		resp.Body.Close()
+	}
+	if requests[http.MethodPost] != 1 {
+		tester.Errorf("Expected the POST to be sent once but got %d requests", requests[http.MethodPost])
+	}
+	if requests[http.MethodHead] != 2 {
+		tester.Errorf("Expected the HEAD to be retried but got %d requests", requests[http.MethodHead])
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"os"
+	"wrs/catalog/ccli/packages/config"
+
+	"github.com/pkg/errors"
+)
+
+// supported values for the minimum tls version
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig() builds the tls configuration for connecting to the catalog
+// from the tls settings of the configuration file
+func NewTLSConfig(tlsSettings config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         tlsSettings.ServerName,
+		InsecureSkipVerify: tlsSettings.InsecureSkipVerify,
+	}
+	if tlsSettings.InsecureSkipVerify {
+		slog.Warn("tls certificate verification is disabled, insecure_skip_verify should only be used for testing")
+	}
+	// set the minimum tls version if given
+	if tlsSettings.MinVersion != "" {
+		version, ok := tlsVersions[tlsSettings.MinVersion]
+		if !ok {
+			return nil, errors.Errorf("invalid tls min_version %q, must be one of 1.0, 1.1, 1.2 or 1.3", tlsSettings.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	// trust the given certificate authorities in addition to the system ones
+	if tlsSettings.CAFile != "" {
+		caData, err := os.ReadFile(tlsSettings.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading tls ca_file")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, errors.Errorf("error reading tls ca_file %s, no pem encoded certificates found", tlsSettings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	// load the client certificate for mutual tls
+	if tlsSettings.CertFile != "" || tlsSettings.KeyFile != "" {
+		if tlsSettings.CertFile == "" || tlsSettings.KeyFile == "" {
+			return nil, errors.New("error reading tls client certificate, both cert_file and key_file must be given")
+		}
+		certificate, err := tls.LoadX509KeyPair(tlsSettings.CertFile, tlsSettings.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading tls client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
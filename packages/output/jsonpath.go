@@ -0,0 +1,275 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonPathSegment is a part of a jsonpath template, which is either literal
+// text, an expression selecting values or a range over the selected values
+type jsonPathSegment struct {
+	text string
+	path []pathStep
+	// segments executed for every selected value of a range
+	body    []jsonPathSegment
+	isRange bool
+}
+
+// pathStep selects the children of a value, either by key or index,
+// or all of them for a wildcard
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	// the path starts at the root value instead of the current one
+	root bool
+}
+
+// parseJSONPath() parses a kubectl style jsonpath template such as
+// '{.name}-{.version}' or '{range .items[*]}{.name}{"\n"}{end}'
+func parseJSONPath(template string) ([]jsonPathSegment, error) {
+	// stack of the segment lists being filled, the last one belongs to the innermost range
+	stack := [][]jsonPathSegment{nil}
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			start = len(template)
+		}
+		if start > 0 {
+			stack[len(stack)-1] = append(stack[len(stack)-1], jsonPathSegment{text: template[:start]})
+			template = template[start:]
+			continue
+		}
+		end := closingBrace(template)
+		if end < 0 {
+			return nil, errors.Errorf("invalid jsonpath, unclosed expression %q", template)
+		}
+		expression := strings.TrimSpace(template[1:end])
+		template = template[end+1:]
+		switch {
+		case expression == "end":
+			if len(stack) == 1 {
+				return nil, errors.New("invalid jsonpath, end without range")
+			}
+			body := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			rangeSegment := &stack[len(stack)-1][len(stack[len(stack)-1])-1]
+			rangeSegment.body = body
+		case strings.HasPrefix(expression, "range "):
+			path, err := parsePath(strings.TrimSpace(strings.TrimPrefix(expression, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], jsonPathSegment{path: path, isRange: true})
+			stack = append(stack, nil)
+		case strings.HasPrefix(expression, `"`):
+			text, err := strconv.Unquote(expression)
+			if err != nil {
+				return nil, errors.Errorf("invalid jsonpath, malformed string %s", expression)
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], jsonPathSegment{text: text})
+		default:
+			path, err := parsePath(expression)
+			if err != nil {
+				return nil, err
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], jsonPathSegment{path: path})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, errors.New("invalid jsonpath, range without end")
+	}
+	return stack[0], nil
+}
+
+// closingBrace() gives the index of the brace closing the expression at
+// the start of the template, ignoring braces in quoted strings
+func closingBrace(template string) int {
+	var quote byte
+	for i := 1; i < len(template); i++ {
+		switch {
+		case quote != 0 && template[i] == '\\':
+			i++
+		case quote != 0 && template[i] == quote:
+			quote = 0
+		case quote == 0 && (template[i] == '"' || template[i] == '\''):
+			quote = template[i]
+		case quote == 0 && template[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// parsePath() parses a path such as .parts[0].name, $.aliases[*] or .*
+func parsePath(expression string) ([]pathStep, error) {
+	var steps []pathStep
+	root := false
+	if strings.HasPrefix(expression, "$") {
+		root = true
+		expression = expression[1:]
+	}
+	if expression == "" || expression == "." {
+		return []pathStep{{root: root}}, nil
+	}
+	for expression != "" {
+		var step pathStep
+		switch expression[0] {
+		case '.':
+			expression = expression[1:]
+			end := strings.IndexAny(expression, ".[")
+			if end < 0 {
+				end = len(expression)
+			}
+			name := expression[:end]
+			expression = expression[end:]
+			if name == "" {
+				return nil, errors.Errorf("invalid jsonpath, empty key in %q", expression)
+			}
+			step = pathStep{key: name, wildcard: name == "*"}
+		case '[':
+			end := strings.IndexByte(expression, ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid jsonpath, unclosed bracket in %q", expression)
+			}
+			selector := strings.TrimSpace(expression[1:end])
+			expression = expression[end+1:]
+			if selector == "*" {
+				step = pathStep{wildcard: true}
+			} else if unquoted := strings.Trim(selector, `'"`); len(selector) >= 2 && unquoted != selector {
+				step = pathStep{key: unquoted}
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, errors.Errorf("invalid jsonpath, bad index [%s]", selector)
+				}
+				step = pathStep{index: index, isIndex: true}
+			}
+		default:
+			return nil, errors.Errorf("invalid jsonpath, expected . or [ at %q", expression)
+		}
+		steps = append(steps, step)
+	}
+	steps[0].root = root
+	return steps, nil
+}
+
+// executeJSONPath() renders the template for the value, values of an
+// expression are separated by spaces and nested values given in compact json
+func executeJSONPath(segments []jsonPathSegment, root *node, current *node, builder *strings.Builder) error {
+	for _, segment := range segments {
+		if segment.path == nil {
+			builder.WriteString(segment.text)
+			continue
+		}
+		selected, err := selectPath(segment.path, root, current)
+		if err != nil {
+			return err
+		}
+		if segment.isRange {
+			// a range over a single array iterates its elements
+			if len(selected) == 1 && selected[0].kind == arrayNode {
+				selected = selected[0].items
+			}
+			for _, item := range selected {
+				if err = executeJSONPath(segment.body, root, item, builder); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for i, value := range selected {
+			if i > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(cell(value))
+		}
+	}
+	return nil
+}
+
+// selectPath() gives the values selected by the path
+func selectPath(path []pathStep, root *node, current *node) ([]*node, error) {
+	selected := []*node{current}
+	if path[0].root {
+		selected = []*node{root}
+	}
+	for _, step := range path {
+		if step.key == "" && !step.wildcard && !step.isIndex {
+			continue
+		}
+		var next []*node
+		for _, value := range selected {
+			switch {
+			case step.wildcard:
+				next = append(next, value.items...)
+				next = append(next, value.values...)
+			case step.isIndex:
+				if value.kind != arrayNode {
+					return nil, errors.Errorf("jsonpath index [%d] applied to a non array value", step.index)
+				}
+				index := step.index
+				if index < 0 {
+					index += len(value.items)
+				}
+				if index < 0 || index >= len(value.items) {
+					return nil, errors.Errorf("jsonpath index [%d] out of range", step.index)
+				}
+				next = append(next, value.items[index])
+			default:
+				child := value.child(step.key)
+				if child == nil {
+					return nil, errors.Errorf("jsonpath key %q not found", step.key)
+				}
+				next = append(next, child)
+			}
+		}
+		selected = next
+	}
+	return selected, nil
+}
+
+// child() gives the value of the object key, keys
+// are matched exactly as they are in the json output
+func (n *node) child(key string) *node {
+	if n.kind != objectNode {
+		return nil
+	}
+	for i, k := range n.keys {
+		if k == key {
+			return n.values[i]
+		}
+	}
+	return nil
+}
+
+// renderJSONPath() gives the result of the jsonpath template for the value
+func renderJSONPath(value interface{}, template string) ([]byte, error) {
+	segments, err := parseJSONPath(template)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toNode(value)
+	if err != nil {
+		return nil, err
+	}
+	var builder strings.Builder
+	if err = executeJSONPath(segments, n, n, &builder); err != nil {
+		return nil, err
+	}
+	return terminate(builder.String()), nil
+}
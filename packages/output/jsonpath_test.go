@@ -0,0 +1,91 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"testing"
+	"wrs/catalog/ccli/packages/graphql"
+
+	"github.com/google/uuid"
+)
+
+// jsonPathParts are parts of the catalog the test templates are applied to
+var jsonPathParts = []graphql.Part{
+	{ID: uuid.MustParse("0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"), Name: "busybox", Version: "1.35.0", FileVerificationCode: "4656", Aliases: []string{"bb", "busybox-1.35"}},
+	{ID: uuid.MustParse("1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f"), Name: "openssl", Version: "3.0", FileVerificationCode: "4657", Aliases: []string{}},
+}
+
+// jsonPathArchive is an archive of the catalog together with its part
+var jsonPathArchive = graphql.Archive{Name: "busybox-1.35.0.tar.bz2", Sha256: "b070", PartID: jsonPathParts[0].ID, Part: jsonPathParts[0]}
+
+// TestJSONPath checks the result of jsonpath templates
+func TestJSONPath(tester *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		template string
+		expected string
+	}{
+		{"field", jsonPathParts, "{[0].name}", "busybox\n"},
+		{"snake case field", jsonPathParts[0], "{.file_verification_code}", "4656\n"},
+		{"field with text", jsonPathParts, "{[0].name}-{[0].version}", "busybox-1.35.0\n"},
+		{"root", jsonPathParts, "{$[1].name}", "openssl\n"},
+		{"index", jsonPathParts, "{[0].aliases[1]}", "busybox-1.35\n"},
+		{"negative index", jsonPathParts, "{[-1].name}", "openssl\n"},
+		{"wildcard", jsonPathParts, "{[*].name}", "busybox openssl\n"},
+		{"wildcard key", jsonPathParts, "{[0].aliases.*}", "bb busybox-1.35\n"},
+		{"range", jsonPathParts, `{range [*]}{.id}{"\t"}{.name}{"\n"}{end}`, "0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f\tbusybox\n1b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f\topenssl\n"},
+		{"nested range", jsonPathParts, `{range [*]}{.name}:{range .aliases}{" "}{.}{end}{"\n"}{end}`, "busybox: bb busybox-1.35\nopenssl:\n"},
+		{"nested object", jsonPathArchive, "{.part_id} {.part.name}", "0b7c1f1e-7c3a-4f55-9f53-1f1f1f1f1f1f busybox\n"},
+		{"quoted key", jsonPathArchive, "{.part['file_verification_code']}", "4656\n"},
+		{"double quoted key", jsonPathParts, `{[0]["version"]}`, "1.35.0\n"},
+		{"quoted brace", jsonPathParts, `{[0].name}{"}"}`, "busybox}\n"},
+	}
+	for _, test := range tests {
+		result, err := renderJSONPath(test.value, test.template)
+		if err != nil {
+			tester.Errorf("%s: failed to render %s: %v", test.name, test.template, err)
+			continue
+		}
+		if string(result) != test.expected {
+			tester.Errorf("%s: expected %q for %s but got %q", test.name, test.expected, test.template, result)
+		}
+	}
+}
+
+// TestJSONPathErrors checks that malformed templates and missing values are errors
+func TestJSONPathErrors(tester *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unclosed expression", "{[0].name"},
+		{"unclosed bracket", "{[0.name}"},
+		{"bad index", "{[x].name}"},
+		{"empty key", "{[0]..name}"},
+		{"missing dot", "{name}"},
+		{"end without range", "{.name}{end}"},
+		{"range without end", "{range [*]}{.name}"},
+		{"malformed string", `{"\q"}`},
+		{"index out of range", "{[2].name}"},
+		{"index of an object", "{[0].name[0]}"},
+		{"missing key", "{[0].release}"},
+		// keys are matched exactly against the json output, not the go fields
+		{"key of the go field", "{[0].FileVerificationCode}"},
+		{"key in other case", "{[0].Name}"},
+	}
+	for _, test := range tests {
+		if result, err := renderJSONPath(jsonPathParts, test.template); err == nil {
+			tester.Errorf("%s: expected an error for %s but got %q", test.name, test.template, result)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Output package renders command results as json, yaml, tables or csv on stdout and
+// writes informational messages to stderr, keeping stdout usable in scripts
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format is an output format for command results
+type Format string
+
+const (
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	Table Format = "table"
+	CSV   Format = "csv"
+)
+
+// output format used if none is configured
+const DefaultFormat = JSON
+
+// Formats lists the supported output formats
+var Formats = []Format{JSON, YAML, Table, CSV}
+
+// ParseFormat() gives the output format of the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return "", errors.Errorf("invalid output format %q, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// Printer writes command results in its format to Out and
+// informational messages to Err
+type Printer struct {
+	Format Format
+	// indentation of json output
+	Indent string
+	// go template or jsonpath template selecting the output, used instead of the format
+	Template string
+	JSONPath string
+	Out      io.Writer
+	Err      io.Writer
+}
+
+// New() creates a printer writing to stdout and stderr
+func New(format Format, indent string) *Printer {
+	return &Printer{Format: format, Indent: indent, Out: os.Stdout, Err: os.Stderr}
+}
+
+// Print() renders the value in the format of the printer. Values are rendered
+// through their json encoding, so all formats show the same fields.
+func (printer *Printer) Print(value interface{}) error {
+	var data []byte
+	var err error
+	switch {
+	case printer.JSONPath != "":
+		data, err = renderJSONPath(value, printer.JSONPath)
+		if err != nil {
+			return errors.Wrapf(err, "error executing jsonpath")
+		}
+	case printer.Template != "":
+		data, err = renderTemplate(value, printer.Template)
+		if err != nil {
+			return errors.Wrapf(err, "error executing template")
+		}
+	case printer.Format == YAML:
+		data, err = renderYAML(value)
+	case printer.Format == Table:
+		data, err = renderTable(value)
+	case printer.Format == CSV:
+		data, err = renderCSV(value)
+	default:
+		data, err = renderJSON(value, printer.Indent)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error rendering %s output", printer.Format)
+	}
+	_, err = printer.Out.Write(data)
+	return err
+}
+
+// Info() writes an informational message, which is not part of the result
+func (printer *Printer) Info(format string, args ...interface{}) {
+	fmt.Fprintf(printer.Err, format+"\n", args...)
+}
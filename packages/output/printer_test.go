@@ -0,0 +1,166 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// printerLicense is a nested struct of the test values
+type printerLicense struct {
+	Expression string `json:"expression"`
+}
+
+// printerPart is a part with nested values like the results of the commands
+type printerPart struct {
+	Name    string          `json:"name"`
+	Size    int64           `json:"size"`
+	License printerLicense  `json:"license"`
+	Aliases []string        `json:"aliases"`
+	Extra   *printerLicense `json:"extra,omitempty"`
+}
+
+// printerParts are parts with different keys since omitted fields are left out
+var printerParts = []printerPart{
+	{Name: "busybox", Size: 10, License: printerLicense{Expression: "GPL-2.0-only"}, Aliases: []string{"bb", "busy,box"}},
+	{Name: "openssl", Size: 2, Aliases: []string{}, Extra: &printerLicense{Expression: "Apache-2.0"}},
+}
+
+// TestPrint checks the rendering of values in the output formats
+func TestPrint(tester *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		value    interface{}
+		expected string
+	}{
+		{"json object", JSON, printerParts[0], `{
+  "name": "busybox",
+  "size": 10,
+  "license": {
+    "expression": "GPL-2.0-only"
+  },
+  "aliases": [
+    "bb",
+    "busy,box"
+  ]
+}
+`},
+		{"json raw message", JSON, json.RawMessage(`{"part":{"name":"busybox"}}`), `{
+  "part": {
+    "name": "busybox"
+  }
+}
+`},
+		{"yaml list", YAML, printerParts, `- name: busybox
+  size: 10
+  license:
+    expression: GPL-2.0-only
+  aliases:
+    - bb
+    - busy,box
+- name: openssl
+  size: 2
+  license:
+    expression: ""
+  aliases: []
+  extra:
+    expression: Apache-2.0
+`},
+		{"yaml keeps strings", YAML, map[string]string{"version": "1.0", "flag": "true"}, `flag: "true"
+version: "1.0"
+`},
+		// nested structs and slices are compact json cells, the columns are the union of the keys
+		{"table list", Table, printerParts, "NAME      SIZE   LICENSE                         ALIASES             EXTRA\n" +
+			"busybox   10     {\"expression\":\"GPL-2.0-only\"}   [\"bb\",\"busy,box\"]   \n" +
+			"openssl   2      {\"expression\":\"\"}               []                  {\"expression\":\"Apache-2.0\"}\n"},
+		{"table object", Table, printerLicense{Expression: "MIT"}, `EXPRESSION
+MIT
+`},
+		{"table scalars", Table, []string{"bb", "busybox"}, `VALUE
+bb
+busybox
+`},
+		{"table line breaks", Table, []printerLicense{{Expression: "a\tb\nc"}}, `EXPRESSION
+a b c
+`},
+		{"csv list", CSV, printerParts, `name,size,license,aliases,extra
+busybox,10,"{""expression"":""GPL-2.0-only""}","[""bb"",""busy,box""]",
+openssl,2,"{""expression"":""""}",[],"{""expression"":""Apache-2.0""}"
+`},
+		{"csv empty list", CSV, []printerPart{}, ""},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		printer := &Printer{Format: test.format, Indent: "  ", Out: &out}
+		if err := printer.Print(test.value); err != nil {
+			tester.Errorf("%s: failed to print: %v", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			tester.Errorf("%s: expected\n%s\nbut got\n%s", test.name, test.expected, out.String())
+		}
+	}
+}
+
+// TestPrintTemplate checks the output of go templates and their errors
+func TestPrintTemplate(tester *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		value    interface{}
+		expected string
+		fails    bool
+	}{
+		{"struct field", "{{.Name}}-{{.Size}}", printerParts[0], "busybox-10\n", false},
+		{"range", "{{range .}}{{.Name}} {{end}}", printerParts, "busybox openssl \n", false},
+		{"json function", "{{json .License}}", printerParts[0], "{\"expression\":\"GPL-2.0-only\"}\n", false},
+		{"raw json is decoded", "{{.part.name}}", json.RawMessage(`{"part":{"name":"busybox"}}`), "busybox\n", false},
+		{"empty output", "{{if false}}x{{end}}", printerParts[0], "", false},
+		{"parse error", "{{.Name", printerParts[0], "", true},
+		{"unknown function", "{{upper .Name}}", printerParts[0], "", true},
+		{"missing field", "{{.Version}}", printerParts[0], "", true},
+		{"malformed raw json", "{{.part}}", json.RawMessage(`{"part":`), "", true},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		printer := &Printer{Format: JSON, Template: test.template, Out: &out}
+		err := printer.Print(test.value)
+		if test.fails {
+			if err == nil {
+				tester.Errorf("%s: expected an error but got %q", test.name, out.String())
+			}
+			continue
+		}
+		if err != nil {
+			tester.Errorf("%s: failed to print: %v", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			tester.Errorf("%s: expected %q but got %q", test.name, test.expected, out.String())
+		}
+	}
+}
+
+// TestParseFormat checks the names of the output formats
+func TestParseFormat(tester *testing.T) {
+	for _, name := range []string{"json", "YAML", "Table", "csv"} {
+		if _, err := ParseFormat(name); err != nil {
+			tester.Errorf("Expected %s to be a format but got %v", name, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		tester.Error("Expected xml not to be a format")
+	}
+}
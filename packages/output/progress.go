@@ -0,0 +1,120 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// width of the bar of a progress line
+const progressBarWidth = 30
+
+// minimum time between two redraws of a progress line
+const progressInterval = 100 * time.Millisecond
+
+// Progress draws a progress line with the bytes transferred, the
+// rate and the estimated time left, redrawn in place on a terminal
+type Progress struct {
+	mutex   sync.Mutex
+	out     io.Writer
+	label   string
+	total   int64
+	current int64
+	start   time.Time
+	drawn   time.Time
+}
+
+// NewProgress() creates a progress line for the transfer of total bytes
+func NewProgress(out io.Writer, label string, total int64) *Progress {
+	return &Progress{out: out, label: label, total: total, start: time.Now()}
+}
+
+// IsTerminal() checks if the writer is a terminal, progress
+// lines are only drawn on terminals
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Update() sets the number of bytes transferred and redraws the line,
+// at most every 100ms. It is safe to call from several goroutines.
+func (progress *Progress) Update(current int64) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.current = current
+	if time.Since(progress.drawn) < progressInterval && current < progress.total {
+		return
+	}
+	progress.draw()
+}
+
+// Done() draws the final state of the line and ends it
+func (progress *Progress) Done() {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.draw()
+	fmt.Fprintln(progress.out)
+}
+
+// draw() redraws the line with the current state
+func (progress *Progress) draw() {
+	progress.drawn = time.Now()
+	elapsed := time.Since(progress.start).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(progress.current) / elapsed
+	}
+	fraction := 1.0
+	if progress.total > 0 {
+		fraction = float64(progress.current) / float64(progress.total)
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	eta := "--"
+	if rate > 0 && progress.current < progress.total {
+		eta = time.Duration(float64(progress.total-progress.current) / rate * float64(time.Second)).Round(time.Second).String()
+	} else if progress.current >= progress.total {
+		eta = "0s"
+	}
+	// the line is cleared to the end since it may be shorter than the last one
+	fmt.Fprintf(progress.out, "\r%s [%s] %3.0f%% %s/%s %s/s ETA %s\033[K", progress.label, bar, fraction*100,
+		formatBytes(float64(progress.current)), formatBytes(float64(progress.total)), formatBytes(rate), eta)
+}
+
+// formatBytes() gives the number of bytes in binary units, e.g. 1.5 GiB
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kinds of json values
+const (
+	scalarNode = iota
+	objectNode
+	arrayNode
+)
+
+// node is a decoded json value which keeps the order of object keys
+type node struct {
+	kind int
+	// keys and values of an object in order
+	keys   []string
+	values []*node
+	// elements of an array
+	items []*node
+	// string, json.Number, bool or nil
+	scalar interface{}
+}
+
+// toNode() decodes the json encoding of the value
+func toNode(value interface{}) (*node, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeNode(decoder)
+}
+
+// decodeNode() decodes the next json value of the decoder
+func decodeNode(decoder *json.Decoder) (*node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return &node{kind: scalarNode, scalar: token}, nil
+	}
+	n := &node{kind: arrayNode}
+	if delim == '{' {
+		n.kind = objectNode
+	}
+	for decoder.More() {
+		if n.kind == objectNode {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key.(string))
+		}
+		child, err := decodeNode(decoder)
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == objectNode {
+			n.values = append(n.values, child)
+		} else {
+			n.items = append(n.items, child)
+		}
+	}
+	// consume the closing delimiter
+	if _, err = decoder.Token(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// renderJSON() gives the indented json encoding of the value
+func renderJSON(value interface{}, indent string) ([]byte, error) {
+	data, err := json.MarshalIndent(value, "", indent)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// renderYAML() gives the yaml encoding of the value's json encoding
+func renderYAML(value interface{}) ([]byte, error) {
+	n, err := toNode(value)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(yamlNode(n)); err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// yamlNode() converts the json value to a yaml node
+func yamlNode(n *node) *yaml.Node {
+	switch n.kind {
+	case objectNode:
+		mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i, key := range n.keys {
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, yamlNode(n.values[i]))
+		}
+		return mapping
+	case arrayNode:
+		sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range n.items {
+			sequence.Content = append(sequence.Content, yamlNode(item))
+		}
+		return sequence
+	}
+	switch scalar := n.scalar.(type) {
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalar}
+	case json.Number:
+		if _, err := scalar.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: scalar.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: scalar.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(scalar)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// rows() flattens the json value into a header and rows of cells. Arrays
+// of objects give one row per object with the union of their keys as
+// columns, a single object gives a single row and other values a single
+// column. Nested values are given in compact json.
+func rows(value interface{}) ([]string, [][]string, error) {
+	n, err := toNode(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := []*node{n}
+	if n.kind == arrayNode {
+		items = n.items
+	}
+	var header []string
+	columns := make(map[string]int)
+	for _, item := range items {
+		if item.kind != objectNode {
+			// arrays of mixed or scalar values are shown in a single column
+			header = []string{"value"}
+			columns = nil
+			break
+		}
+		for _, key := range item.keys {
+			if _, ok := columns[key]; !ok {
+				columns[key] = len(header)
+				header = append(header, key)
+			}
+		}
+	}
+	var cells [][]string
+	for _, item := range items {
+		row := make([]string, len(header))
+		if columns == nil {
+			row[0] = cell(item)
+		} else {
+			for i, key := range item.keys {
+				row[columns[key]] = cell(item.values[i])
+			}
+		}
+		cells = append(cells, row)
+	}
+	return header, cells, nil
+}
+
+// cell() gives the text of a table cell
+func cell(n *node) string {
+	if n.kind != scalarNode {
+		return compactJSON(n)
+	}
+	if n.scalar == nil {
+		return ""
+	}
+	return fmt.Sprint(n.scalar)
+}
+
+// compactJSON() gives the compact json encoding of the value
+func compactJSON(n *node) string {
+	var builder strings.Builder
+	switch n.kind {
+	case objectNode:
+		builder.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				builder.WriteByte(',')
+			}
+			encodedKey, _ := json.Marshal(key)
+			builder.Write(encodedKey)
+			builder.WriteByte(':')
+			builder.WriteString(compactJSON(n.values[i]))
+		}
+		builder.WriteByte('}')
+	case arrayNode:
+		builder.WriteByte('[')
+		for i, item := range n.items {
+			if i > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteString(compactJSON(item))
+		}
+		builder.WriteByte(']')
+	default:
+		encoded, _ := json.Marshal(n.scalar)
+		builder.Write(encoded)
+	}
+	return builder.String()
+}
+
+// renderTable() gives the value as a table with aligned columns
+func renderTable(value interface{}) ([]byte, error) {
+	header, cells, err := rows(value)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 3, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	for _, row := range cells {
+		// tabs and line breaks would break the alignment
+		for i := range row {
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(row[i])
+		}
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	if err = writer.Flush(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// renderCSV() gives the value as comma separated values with a header line
+func renderCSV(value interface{}) ([]byte, error) {
+	header, cells, err := rows(value)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if len(header) > 0 {
+		if err = writer.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.WriteAll(cells); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
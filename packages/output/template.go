@@ -0,0 +1,57 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+)
+
+// functions available in go templates in addition to the builtin ones
+var templateFuncs = template.FuncMap{
+	// json gives the compact json encoding of a value
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// renderTemplate() gives the result of the go template for the value. Structs
+// such as parts are given to the template as is, e.g. '{{.Name}}-{{.Version}}',
+// while raw json such as query responses is decoded first.
+func renderTemplate(value interface{}, text string) ([]byte, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := value.(json.RawMessage); ok {
+		var decoded interface{}
+		if err = json.Unmarshal(raw, &decoded); err != nil {
+			return nil, err
+		}
+		value = decoded
+	}
+	var builder strings.Builder
+	if err = tmpl.Execute(&builder, value); err != nil {
+		return nil, err
+	}
+	return terminate(builder.String()), nil
+}
+
+// terminate() ends the output with a line break unless it is empty or already ends with one
+func terminate(text string) []byte {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return []byte(text)
+}